@@ -0,0 +1,119 @@
+package main
+
+/*
+	devcert generates a self-signed TLS certificate and private key for
+	running snippetbox over HTTPS during development:
+
+	go run ./cmd/devcert
+	go run ./cmd/web -tls-cert=./tls/cert.pem -tls-key=./tls/key.pem
+
+	Browsers will warn that the certificate isn't trusted. Never use it in
+	production.
+*/
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func main() {
+	dir := flag.String("dir", "./tls", "Directory to write cert.pem and key.pem to")
+	hosts := flag.String("host", "localhost,127.0.0.1,::1", "Comma-separated hostnames and IPs the certificate is valid for")
+	validFor := flag.Duration("valid-for", 365*24*time.Hour, "How long the certificate is valid for")
+
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	err := generate(*dir, strings.Split(*hosts, ","), *validFor)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	logger.Info("wrote certificate", "cert", filepath.Join(*dir, "cert.pem"), "key", filepath.Join(*dir, "key.pem"))
+}
+
+func generate(dir string, hosts []string, validFor time.Duration) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	notBefore := time.Now()
+
+	template := x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"Snippetbox Development"}},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	for _, h := range hosts {
+		h = strings.TrimSpace(h)
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if h != "" {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return err
+	}
+
+	err = writePEM(filepath.Join(dir, "cert.pem"), "CERTIFICATE", der, 0o644)
+	if err != nil {
+		return err
+	}
+
+	// The private key must only be readable by its owner.
+	return writePEM(filepath.Join(dir, "key.pem"), "PRIVATE KEY", keyBytes, 0o600)
+}
+
+func writePEM(path string, blockType string, b []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	err = pem.Encode(f, &pem.Block{Type: blockType, Bytes: b})
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Define a snippet type matching the JSON encoding of models.Snippet. We
+// don't import the models package, so that the client doesn't depend on the
+// server's database drivers.
+type snippet struct {
+	ID       int       `json:"id"`
+	Title    string    `json:"title"`
+	Content  string    `json:"content"`
+	Language string    `json:"language"`
+	Created  time.Time `json:"created"`
+	Expires  time.Time `json:"expires"`
+	Tags     []string  `json:"tags"`
+}
+
+// Define an apiError type to hold the error responses from the JSON API.
+type apiError struct {
+	Status      int               `json:"status"`
+	Message     string            `json:"message"`
+	FieldErrors map[string]string `json:"field_errors"`
+}
+
+func (e *apiError) Error() string {
+	msg := e.Message
+
+	fields := make([]string, 0, len(e.FieldErrors))
+	for field := range e.FieldErrors {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+
+	for _, field := range fields {
+		msg += fmt.Sprintf("\n  %s: %s", field, e.FieldErrors[field])
+	}
+
+	return msg
+}
+
+// Define a client type which sends requests to a snippetbox server, with the
+// API token (if any) in the Authorization header.
+type client struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+func newClient(cfg config) *client {
+	return &client{
+		server: strings.TrimSuffix(cfg.Server, "/"),
+		token:  cfg.Token,
+		http:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends a request with an optional JSON body. A successful JSON response
+// is decoded into dst, while an error response is returned as an *apiError.
+func (c *client) do(method, path string, header http.Header, body any, dst any) error {
+	var r io.Reader
+
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.server+path, r)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var env struct {
+			Error *apiError `json:"error"`
+		}
+
+		if json.NewDecoder(resp.Body).Decode(&env) != nil || env.Error == nil {
+			return &apiError{Status: resp.StatusCode, Message: resp.Status}
+		}
+
+		return env.Error
+	}
+
+	if dst == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
+
+// viewURL returns the URL of the page for a snippet.
+func (c *client) viewURL(id int) string {
+	return fmt.Sprintf("%s/snippet/view/%d", c.server, id)
+}
+
+// create creates a snippet, returning it along with its delete key.
+func (c *client) create(title, content, language string, expires int, tags []string) (snippet, string, error) {
+	body := map[string]any{
+		"title":    title,
+		"content":  content,
+		"language": language,
+		"expires":  expires,
+		"tags":     tags,
+	}
+
+	var resp struct {
+		Snippet   snippet `json:"snippet"`
+		DeleteKey string  `json:"delete_key"`
+	}
+
+	err := c.do(http.MethodPost, "/api/v1/snippets", nil, body, &resp)
+
+	return resp.Snippet, resp.DeleteKey, err
+}
+
+// raw writes the content of a snippet to w.
+func (c *client) raw(w io.Writer, id int) error {
+	resp, err := c.http.Get(fmt.Sprintf("%s/snippet/raw/%d", c.server, id))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("snippet %d not found", id)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+
+	return err
+}
+
+// search returns a page of the snippets matching a query, and whether there
+// is another page.
+func (c *client) search(query, tag string, page int) ([]snippet, bool, error) {
+	q := url.Values{"q": {query}, "page": {strconv.Itoa(page)}}
+	if tag != "" {
+		q.Set("tag", tag)
+	}
+
+	var resp struct {
+		Snippets []snippet `json:"snippets"`
+		HasMore  bool      `json:"has_more"`
+	}
+
+	err := c.do(http.MethodGet, "/api/v1/snippets/search?"+q.Encode(), nil, nil, &resp)
+
+	return resp.Snippets, resp.HasMore, err
+}
+
+// delete deletes a snippet, using its delete key if key isn't empty.
+func (c *client) delete(id int, key string) error {
+	header := make(http.Header)
+	if key != "" {
+		header.Set("X-Delete-Key", key)
+	}
+
+	return c.do(http.MethodDelete, fmt.Sprintf("/api/v1/snippets/%d", id), header, nil, nil)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// defaultServer is the server used if none is configured, matching the
+// default address of cmd/web.
+const defaultServer = "http://localhost:4000"
+
+// Define a config type to hold the contents of the config file, which looks
+// like this:
+//
+//	{
+//		"server": "https://snippets.example.com",
+//		"token": "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+//	}
+type config struct {
+	Server string `json:"server"`
+	Token  string `json:"token"`
+}
+
+// configPath returns the default location of the config file, which is
+// snippet/config.json in the user's config directory (~/.config on Linux).
+func configPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "snippet.json"
+	}
+
+	return filepath.Join(dir, "snippet", "config.json")
+}
+
+// loadConfig reads the config file. A missing file isn't an error, since
+// everything can be set with flags or environment variables instead.
+func loadConfig(path string) (config, error) {
+	var cfg config
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	err = json.Unmarshal(b, &cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// saveConfig writes the config file. It is only readable by the user, since
+// it holds their API token.
+func saveConfig(path string, cfg config) error {
+	b, err := json.MarshalIndent(cfg, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(b, '\n'), 0600)
+}
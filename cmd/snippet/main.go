@@ -0,0 +1,288 @@
+package main
+
+/*
+	snippet is a command-line client for the snippetbox JSON API:
+
+	tail -n 50 app.log | snippet create --title "App log" --expires 7
+	snippet create --tags go,example main.go
+	snippet get 42 > main.go
+	snippet search --tag go "http handler"
+	snippet delete --key 3c8d... 42
+
+	The server URL and API token are read from a JSON config file (see
+	configPath), which can be written with the config command:
+
+	snippet config --server https://snippets.example.com --token ABC...
+
+	The SNIPPETBOX_URL and SNIPPETBOX_TOKEN environment variables and the
+	--server and --token flags override the values from the file, in that
+	order.
+*/
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const usage = `Usage: snippet [--server URL] [--token TOKEN] [--config FILE] <command> [arguments]
+
+Commands:
+  create [--title T] [--expires DAYS] [--lang LANG] [--tags a,b] [FILE...]
+        create a snippet from each file, or from stdin if there are none
+  get ID
+        write the content of a snippet to stdout
+  search [--tag TAG] [--page N] QUERY...
+        search the snippets
+  delete [--key KEY] ID...
+        delete snippets, with their delete key or an API token
+  config [--server URL] [--token TOKEN]
+        save the server URL and API token to the config file
+`
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "snippet: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("snippet", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+
+	server := fs.String("server", "", "Server URL")
+	token := fs.String("token", "", "API token")
+	configFile := fs.String("config", configPath(), "Config file")
+
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	cfg.Server = cmp.Or(*server, os.Getenv("SNIPPETBOX_URL"), cfg.Server, defaultServer)
+	cfg.Token = cmp.Or(*token, os.Getenv("SNIPPETBOX_TOKEN"), cfg.Token)
+
+	c := newClient(cfg)
+	cmd, args := fs.Arg(0), fs.Args()[1:]
+
+	switch cmd {
+	case "create":
+		return create(c, args)
+	case "get":
+		return get(c, args)
+	case "search":
+		return search(c, args)
+	case "delete":
+		return remove(c, args)
+	case "config":
+		return configure(*configFile, args)
+	default:
+		return fmt.Errorf("unknown command %q (run snippet -h for usage)", cmd)
+	}
+}
+
+// create creates a snippet from each of the files named in args, or from
+// stdin if there are none, and prints their URLs. The delete keys are printed
+// to stderr, so that piping the output gives just the URLs.
+func create(c *client, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+
+	title := fs.String("title", "", "Title (default the file name)")
+	expires := fs.Int("expires", 365, "Days until the snippet expires: 1, 7 or 365")
+	lang := fs.String("lang", "", "Language to highlight the snippet as (default detected by the server)")
+	tags := fs.String("tags", "", "Comma-separated tags")
+
+	fs.Parse(args)
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	var tagList []string
+	for _, tag := range strings.Split(*tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tagList = append(tagList, tag)
+		}
+	}
+
+	for _, file := range files {
+		content, err := readInput(file)
+		if err != nil {
+			return err
+		}
+
+		name := *title
+		if name == "" && file != "-" {
+			name = filepath.Base(file)
+		}
+
+		s, key, err := c.create(cmp.Or(name, "Untitled"), content, *lang, *expires, tagList)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(c.viewURL(s.ID))
+		fmt.Fprintf(os.Stderr, "delete key for snippet %d: %s\n", s.ID, key)
+	}
+
+	return nil
+}
+
+// readInput returns the content of a file, or of stdin if the name is "-".
+func readInput(name string) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+
+	if name == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(name)
+	}
+
+	return string(b), err
+}
+
+func get(c *client, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: snippet get ID")
+	}
+
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+
+	return c.raw(os.Stdout, id)
+}
+
+// search prints a table of the snippets matching a query.
+func search(c *client, args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+
+	tag := fs.String("tag", "", "Only show snippets with this tag")
+	page := fs.Int("page", 1, "Page of results to show")
+
+	fs.Parse(args)
+
+	snippets, more, err := c.search(strings.Join(fs.Args(), " "), *tag, *page)
+	if err != nil {
+		return err
+	}
+
+	if len(snippets) == 0 {
+		fmt.Fprintln(os.Stderr, "no snippets found")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tTAGS\tEXPIRES")
+
+	for _, s := range snippets {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.ID, s.Title, strings.Join(s.Tags, ","), s.Expires.Local().Format("2006-01-02 15:04"))
+	}
+
+	err = tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	if more {
+		fmt.Fprintf(os.Stderr, "more results with --page %d\n", *page+1)
+	}
+
+	return nil
+}
+
+// remove deletes each of the snippets in args. It is called remove because
+// delete is a builtin.
+func remove(c *client, args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+
+	key := fs.String("key", "", "Delete key of the snippet (not needed with the owner's API token)")
+
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return errors.New("usage: snippet delete [--key KEY] ID...")
+	}
+
+	for _, arg := range fs.Args() {
+		id, err := parseID(arg)
+		if err != nil {
+			return err
+		}
+
+		err = c.delete(id, *key)
+		if err != nil {
+			return fmt.Errorf("snippet %d: %w", id, err)
+		}
+
+		fmt.Fprintf(os.Stderr, "deleted snippet %d\n", id)
+	}
+
+	return nil
+}
+
+// configure updates the config file with the values of the flags in args, and
+// prints the resulting config.
+func configure(path string, args []string) error {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+
+	server := fs.String("server", "", "Server URL")
+	token := fs.String("token", "", "API token")
+
+	fs.Parse(args)
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	if *server != "" || *token != "" {
+		cfg.Server = cmp.Or(*server, cfg.Server)
+		cfg.Token = cmp.Or(*token, cfg.Token)
+
+		err = saveConfig(path, cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Don't print the whole token, since it grants access to the account.
+	masked := "(none)"
+	if cfg.Token != "" {
+		masked = cfg.Token[:min(4, len(cfg.Token))] + "..."
+	}
+
+	fmt.Printf("config: %s\nserver: %s\ntoken:  %s\n", path, cmp.Or(cfg.Server, defaultServer), masked)
+
+	return nil
+}
+
+func parseID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid snippet ID %q", s)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Galbeyte1/snippetbox/internal/models"
+)
+
+// The apiSnippetList handler returns the latest snippets as JSON, only
+// including those with the tag in the tag query string parameter, if any.
+func (app *application) apiSnippetList(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.Latest(strings.ToLower(r.URL.Query().Get("tag")))
+	if err != nil {
+		app.serverErrorJSON(w, r, err)
+		return
+	}
+
+	// Make sure we send an empty JSON array rather than null when there are
+	// no snippets.
+	if snippets == nil {
+		snippets = []models.Snippet{}
+	}
+
+	err = app.writeJSON(w, OK, envelope{"snippets": snippets}, nil)
+	if err != nil {
+		app.serverErrorJSON(w, r, err)
+	}
+}
+
+// The apiSnippetSearch handler returns a page of the snippets matching the q
+// query string parameter as JSON, along with the page number and whether
+// there is a next page.
+func (app *application) apiSnippetSearch(w http.ResponseWriter, r *http.Request) {
+	var form snippetSearchForm
+
+	err := app.decodeQuery(r, &form)
+	if err != nil {
+		app.errorJSON(w, r, BAD_REQUEST, "the page parameter must be a number")
+		return
+	}
+
+	form.validate()
+
+	if !form.Valid() {
+		app.failedValidationJSON(w, r, form.FieldErrors)
+		return
+	}
+
+	snippets, more, err := app.snippets.Search(form.Query, form.Tag, form.Page)
+	if err != nil {
+		app.serverErrorJSON(w, r, err)
+		return
+	}
+
+	if snippets == nil {
+		snippets = []models.Snippet{}
+	}
+
+	err = app.writeJSON(w, OK, envelope{"snippets": snippets, "page": form.Page, "has_more": more}, nil)
+	if err != nil {
+		app.serverErrorJSON(w, r, err)
+	}
+}
+
+// The apiSnippetView handler returns a specific snippet as JSON.
+func (app *application) apiSnippetView(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.errorJSON(w, r, http.StatusNotFound, "the requested snippet could not be found")
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.errorJSON(w, r, http.StatusNotFound, "the requested snippet could not be found")
+		} else {
+			app.serverErrorJSON(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, OK, envelope{"snippet": snippet}, nil)
+	if err != nil {
+		app.serverErrorJSON(w, r, err)
+	}
+}
+
+// The apiSnippetCreate handler creates a new snippet from a JSON request body,
+// using the same validation rules as the HTML form.
+func (app *application) apiSnippetCreate(w http.ResponseWriter, r *http.Request) {
+	// Snippets created with an API token are attributed to the token's owner,
+	// as long as the token has the write scope. Requests without a token
+	// create anonymous snippets, just like the HTML form.
+	userID := 0
+
+	if token, ok := app.apiToken(r); ok {
+		if !token.Allows(models.ScopeWrite) {
+			app.errorJSON(w, r, FORBIDDEN, "this token does not have the write scope")
+			return
+		}
+
+		userID = token.UserID
+	}
+
+	var form snippetCreateForm
+
+	err := app.readJSON(w, r, &form)
+	if err != nil {
+		app.errorJSON(w, r, BAD_REQUEST, err.Error())
+		return
+	}
+
+	form.validate()
+
+	if !form.Valid() {
+		app.failedValidationJSON(w, r, form.FieldErrors)
+		return
+	}
+
+	id, key, err := app.snippets.Insert(form.Title, form.Content, form.Language, form.Expires, userID, 0, form.TagList)
+	if err != nil {
+		app.serverErrorJSON(w, r, err)
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		app.serverErrorJSON(w, r, err)
+		return
+	}
+
+	// Include a Location header pointing at the new snippet, and the delete
+	// key in the body since this is the only time it is available.
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/snippets/%d", id))
+
+	err = app.writeJSON(w, CREATED, envelope{"snippet": snippet, "delete_key": key}, headers)
+	if err != nil {
+		app.serverErrorJSON(w, r, err)
+	}
+}
+
+// The apiSnippetDelete handler deletes a snippet. The owner of the snippet can
+// delete it with an API token that has the write scope. Anyone else needs the
+// delete key, in the X-Delete-Key header or the key query string parameter.
+func (app *application) apiSnippetDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.errorJSON(w, r, http.StatusNotFound, "the requested snippet could not be found")
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.errorJSON(w, r, http.StatusNotFound, "the requested snippet could not be found")
+		} else {
+			app.serverErrorJSON(w, r, err)
+		}
+		return
+	}
+
+	key := r.Header.Get("X-Delete-Key")
+	if key == "" {
+		key = r.URL.Query().Get("key")
+	}
+
+	token, ok := app.apiToken(r)
+	isOwner := ok && snippet.UserID != 0 && token.UserID == snippet.UserID
+
+	switch {
+	case isOwner && token.Allows(models.ScopeWrite):
+		err = app.snippets.Delete(snippet.ID)
+	case key != "":
+		err = app.snippets.DeleteWithKey(snippet.ID, key)
+	case isOwner:
+		app.errorJSON(w, r, FORBIDDEN, "this token does not have the write scope")
+		return
+	default:
+		app.errorJSON(w, r, FORBIDDEN, "you must own the snippet or provide its delete key")
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			app.errorJSON(w, r, http.StatusNotFound, "the requested snippet could not be found")
+		case errors.Is(err, models.ErrInvalidCredentials):
+			app.errorJSON(w, r, FORBIDDEN, "the delete key is not valid")
+		default:
+			app.serverErrorJSON(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, OK, envelope{"message": "snippet successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorJSON(w, r, err)
+	}
+}
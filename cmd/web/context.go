@@ -0,0 +1,10 @@
+package main
+
+// Define a custom contextKey type so that the keys we store in the request
+// context can't collide with keys set by third-party packages.
+type contextKey string
+
+const (
+	isAuthenticatedContextKey = contextKey("isAuthenticated")
+	apiTokenContextKey        = contextKey("apiToken")
+)
@@ -3,9 +3,15 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
+	"github.com/Galbeyte1/snippetbox/internal/diff"
+	"github.com/Galbeyte1/snippetbox/internal/highlight"
 	"github.com/Galbeyte1/snippetbox/internal/models"
 	"github.com/Galbeyte1/snippetbox/internal/validator"
 )
@@ -18,12 +24,131 @@ const (
 	SEE_OTHER     = http.StatusSeeOther
 	BAD_REQUEST   = http.StatusBadRequest
 	UNPROCESSABLE = http.StatusUnprocessableEntity
+	FORBIDDEN     = http.StatusForbidden
 )
 
+// The HTML form sends the tags as a single Tags string separated by spaces or
+// commas, while JSON API clients send TagList as an array instead. ParentID
+// is the ID of the snippet being forked, if any.
 type snippetCreateForm struct {
-	Title               string
-	Content             string
-	Expires             int
+	Title               string   `form:"title" json:"title"`
+	Content             string   `form:"content" json:"content"`
+	Language            string   `form:"language" json:"language"`
+	Expires             int      `form:"expires" json:"expires"`
+	Tags                string   `form:"tags" json:"-"`
+	TagList             []string `form:"-" json:"tags"`
+	ParentID            int      `form:"parent_id" json:"-"`
+	validator.Validator `form:"-" json:"-"`
+}
+
+// The limits on the tags of a snippet.
+const (
+	maxTags      = 5
+	maxTagLength = 30
+)
+
+// validate runs the checks shared by the create and edit snippet forms. It
+// also fills in TagList from Tags, if the form came from the HTML page.
+func (form *snippetCreateForm) validate() {
+	form.validateFields()
+	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "expires", "This field must be equal 1, 7, or 365")
+}
+
+// validateEdit is like validate, but also accepts an expiry of 0, which the
+// edit form sends to keep the snippet's current expiry.
+func (form *snippetCreateForm) validateEdit() {
+	form.validateFields()
+	form.CheckField(validator.PermittedValue(form.Expires, 0, 1, 7, 365), "expires", "This field must be equal 0, 1, 7, or 365")
+}
+
+// validateFields checks every field of the form except the expiry.
+func (form *snippetCreateForm) validateFields() {
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Content, 1000), "content", "This field cannot be more than 1000 characters long")
+
+	// An empty language means that it should be detected automatically.
+	if form.Language != "" {
+		_, ok := highlight.Lookup(form.Language)
+		form.CheckField(ok, "language", "This field must be one of the listed languages")
+	}
+
+	if form.TagList == nil {
+		form.TagList = splitTags(form.Tags)
+	}
+	form.TagList = normalizeTags(form.TagList)
+
+	form.CheckField(len(form.TagList) <= maxTags, "tags", fmt.Sprintf("This field cannot have more than %d tags", maxTags))
+	for _, tag := range form.TagList {
+		form.CheckField(validator.MaxChars(tag, maxTagLength), "tags", fmt.Sprintf("Each tag cannot be more than %d characters long", maxTagLength))
+		form.CheckField(validator.Matches(tag, validator.TagRX), "tags", "Tags can only contain letters, numbers and hyphens")
+	}
+}
+
+// Create a new snippetSearchForm struct for the search page. Its fields come
+// from the query string, rather than a POST body.
+type snippetSearchForm struct {
+	Query               string `form:"q"`
+	Tag                 string `form:"tag"`
+	Page                int    `form:"page"`
+	validator.Validator `form:"-"`
+}
+
+// validate runs the checks shared by the HTML and JSON API search endpoints.
+// A missing page number means the first page.
+func (form *snippetSearchForm) validate() {
+	if form.Page == 0 {
+		form.Page = 1
+	}
+
+	form.CheckField(validator.NotBlank(form.Query), "q", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Query, 100), "q", "This field cannot be more than 100 characters long")
+	form.CheckField(form.Page > 0, "page", "This field must be a positive number")
+
+	form.Tag = strings.ToLower(strings.TrimSpace(form.Tag))
+	if form.Tag != "" {
+		form.CheckField(validator.Matches(form.Tag, validator.TagRX), "tag", "Tags can only contain letters, numbers and hyphens")
+	}
+}
+
+// Create a new snippetListForm struct for the query string parameters of the
+// snippet listing page.
+type snippetListForm struct {
+	Sort                string `form:"sort"`
+	After               string `form:"after"`
+	Before              string `form:"before"`
+	validator.Validator `form:"-"`
+}
+
+// Create a new snippetHistoryForm struct for the query string parameters of
+// the history page: the versions of the two revisions to compare, and
+// whether to show a "unified" or "split" (side-by-side) diff.
+type snippetHistoryForm struct {
+	From int    `form:"from"`
+	To   int    `form:"to"`
+	View string `form:"view"`
+}
+
+// Create a new tokenCreateForm struct for the API tokens settings page.
+type tokenCreateForm struct {
+	Name                string `form:"name"`
+	Scope               string `form:"scope"`
+	validator.Validator `form:"-"`
+}
+
+// Create a new userSignupForm struct.
+type userSignupForm struct {
+	Name                string `form:"name"`
+	Email               string `form:"email"`
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+// Create a new userLoginForm struct.
+type userLoginForm struct {
+	Email               string `form:"email"`
+	Password            string `form:"password"`
 	validator.Validator `form:"-"`
 }
 
@@ -31,7 +156,7 @@ type snippetCreateForm struct {
 // "Hello from Snippetbox" as a the response body.
 func (app *application) home(w http.ResponseWriter, r *http.Request) {
 
-	snippets, err := app.snippets.Latest()
+	snippets, err := app.snippets.Latest("")
 	if err != nil {
 		app.serverError(w, r, err)
 		return
@@ -43,6 +168,197 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 	app.render(w, r, OK, "home.tmpl", data)
 }
 
+// The snippetList handler shows every unexpired snippet, a page at a time,
+// sorted by the sort query string parameter.
+func (app *application) snippetList(w http.ResponseWriter, r *http.Request) {
+	app.renderSnippetList(w, r, "/snippets", "")
+}
+
+// The tagView handler works like snippetList, but only shows the snippets
+// with the tag in the URL path.
+func (app *application) tagView(w http.ResponseWriter, r *http.Request) {
+	tag := r.PathValue("name")
+	if !validator.Matches(tag, validator.TagRX) || !validator.MaxChars(tag, maxTagLength) {
+		http.NotFound(w, r)
+		return
+	}
+
+	app.renderSnippetList(w, r, "/tag/"+tag, tag)
+}
+
+// renderSnippetList renders a page of the snippet listing at path, only
+// including snippets with the given tag unless it is empty.
+func (app *application) renderSnippetList(w http.ResponseWriter, r *http.Request, path string, tag string) {
+	var form snippetListForm
+
+	err := app.decodeQuery(r, &form)
+	if err != nil {
+		app.clientError(w, BAD_REQUEST)
+		return
+	}
+
+	if form.Sort == "" {
+		form.Sort = models.SortNewest
+	}
+
+	form.CheckField(validator.PermittedValue(form.Sort, models.SortNewest, models.SortExpiring, models.SortTitle), "sort", "This field must be equal to newest, expiring or title")
+
+	if !form.Valid() {
+		app.clientError(w, BAD_REQUEST)
+		return
+	}
+
+	page, err := app.snippets.List(models.ListOptions{Sort: form.Sort, After: form.After, Before: form.Before, Tag: tag})
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			app.clientError(w, BAD_REQUEST)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = form
+	data.Snippets = page.Snippets
+	data.Tag = tag
+	data.ListURL = path
+	data.Pagination = newCursorPagination(path, url.Values{"sort": {form.Sort}}, page)
+
+	app.render(w, r, OK, "list.tmpl", data)
+}
+
+// The snippetRaw handler serves the content of a snippet as plain text, for
+// use from scripts and the command line.
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	app.serveSnippetContent(w, r, snippet)
+}
+
+// The snippetDownload handler works like snippetRaw, but asks the browser to
+// save the content to a file named after the snippet's title and language.
+func (app *application) snippetDownload(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": snippetFilename(snippet)})
+	w.Header().Set("Content-Disposition", disposition)
+
+	app.serveSnippetContent(w, r, snippet)
+}
+
+// The snippetDiff handler compares the content of the snippets identified by
+// the {a} and {b} path values, as a unified diff or, with ?view=split, a
+// side-by-side one.
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+	a, ok := app.snippetFromPathValue(w, r, "a")
+	if !ok {
+		return
+	}
+
+	b, ok := app.snippetFromPathValue(w, r, "b")
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Comparison = &snippetComparison{
+		A:     a,
+		B:     b,
+		Split: r.URL.Query().Get("view") == "split",
+		Hunks: diff.Hunks(diff.Lines(a.Content, b.Content), diffContext),
+	}
+
+	app.render(w, r, OK, "diff.tmpl", data)
+}
+
+// The snippetPatch handler serves the differences between two snippets as a
+// unified diff file, which turns the content of the first snippet into the
+// second when applied with patch. It is empty if their content is the same.
+func (app *application) snippetPatch(w http.ResponseWriter, r *http.Request) {
+	a, ok := app.snippetFromPathValue(w, r, "a")
+	if !ok {
+		return
+	}
+
+	b, ok := app.snippetFromPathValue(w, r, "b")
+	if !ok {
+		return
+	}
+
+	patch := diff.Unified("a/"+snippetFilename(a), "b/"+snippetFilename(b), a.Content, b.Content, diffContext)
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": fmt.Sprintf("snippet-%d-%d.patch", a.ID, b.ID)})
+
+	w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+	w.Header().Set("Content-Disposition", disposition)
+
+	io.WriteString(w, patch)
+}
+
+// The highlightCSS handler serves the stylesheet for the syntax highlighted
+// snippet content. It is generated by the highlighting library rather than
+// kept in ui/static, so that it always matches the HTML it produces.
+func (app *application) highlightCSS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+
+	w.Write(highlight.CSS())
+}
+
+// The snippetSearch handler shows the snippets matching the q query string
+// parameter, a page at a time, with the matching words highlighted.
+func (app *application) snippetSearch(w http.ResponseWriter, r *http.Request) {
+	var form snippetSearchForm
+
+	err := app.decodeQuery(r, &form)
+	if err != nil {
+		app.clientError(w, BAD_REQUEST)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = form
+
+	// If no query has been submitted yet, just show the empty search form
+	// rather than complaining that the query is blank.
+	if !r.URL.Query().Has("q") {
+		app.render(w, r, OK, "search.tmpl", data)
+		return
+	}
+
+	form.validate()
+
+	if !form.Valid() {
+		data.Form = form
+		app.render(w, r, UNPROCESSABLE, "search.tmpl", data)
+		return
+	}
+
+	snippets, more, err := app.snippets.Search(form.Query, form.Tag, form.Page)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	query := url.Values{"q": {form.Query}}
+	if form.Tag != "" {
+		query.Set("tag", form.Tag)
+	}
+
+	data.Form = form
+	data.Snippets = snippets
+	data.Pagination = newPagination("/snippet/search", query, form.Page, more)
+
+	app.render(w, r, OK, "search.tmpl", data)
+}
+
 // Add a snippetView handler function.
 func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(r.PathValue("id"))
@@ -61,8 +377,17 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	forks, err := app.snippets.Forks(snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
 	data := app.newTemplateData(r)
 	data.Snippet = snippet
+	data.Forks = forks
+	data.IsOwner = app.isOwner(r, snippet)
+	data.DeleteKey = app.sessionManager.PopString(r.Context(), "deleteKey")
 
 	app.render(w, r, OK, "view.tmpl", data)
 }
@@ -79,6 +404,26 @@ func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
 	app.render(w, r, OK, "create.tmpl", data)
 }
 
+// The snippetFork handler shows the create snippet form filled in with a copy
+// of an existing snippet. The new snippet records the original as its parent.
+func (app *application) snippetFork(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = snippetCreateForm{
+		Title:    snippet.Title,
+		Content:  snippet.Content,
+		Language: snippet.Language,
+		Expires:  365,
+		ParentID: snippet.ID,
+	}
+
+	app.render(w, r, OK, "create.tmpl", data)
+}
+
 // Add a snippetCreatePost handler funciton
 func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request) {
 
@@ -90,11 +435,7 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
-	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
-	form.CheckField(validator.NotBlank(form.Title), "content", "This field cannot be blank")
-	form.CheckField(validator.MaxChars(form.Title, 1000), "content", "This field cannot be more than 1000 characters long")
-	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "This field must be equal 1, 7, or 365")
+	form.validate()
 
 	if !form.Valid() {
 		data := app.newTemplateData(r)
@@ -103,11 +444,477 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	// Only link a fork to the original snippet if it still exists, since it
+	// may have expired or been deleted while the form was being filled in.
+	if form.ParentID != 0 {
+		_, err = app.snippets.Get(form.ParentID)
+		if errors.Is(err, models.ErrNoRecord) {
+			form.ParentID = 0
+		} else if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	// Attribute the snippet to the logged-in user, if there is one. Anonymous
+	// snippets are stored with a user ID of 0.
+	id, key, err := app.snippets.Insert(form.Title, form.Content, form.Language, form.Expires, app.authenticatedUserID(r), form.ParentID, form.TagList)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
+	// Store the delete key in the session so that it can be shown exactly
+	// once on the next page. Only a hash of the key is stored with the
+	// snippet, so the plain-text key is kept in the sessions table until then.
+	app.sessionManager.Put(r.Context(), "deleteKey", key)
+
+	// Use the Put() method to add a string value ("Snippet successfully
+	// created!") and the corresponding key ("flash") to the session data.
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
+
 	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), SEE_OTHER)
 }
+
+func (app *application) snippetEdit(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	// Prefill the form with the snippet. An expiry of 0 keeps its current
+	// expiry, rather than silently changing it.
+	data.Form = snippetCreateForm{
+		Title:    snippet.Title,
+		Content:  snippet.Content,
+		Language: snippet.Language,
+		Expires:  0,
+		Tags:     strings.Join(snippet.Tags, " "),
+	}
+
+	app.render(w, r, OK, "edit.tmpl", data)
+}
+
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	var form snippetCreateForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, BAD_REQUEST)
+		return
+	}
+
+	form.validateEdit()
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, r, UNPROCESSABLE, "edit.tmpl", data)
+		return
+	}
+
+	// The snippet may have expired since it was fetched above.
+	err = app.snippets.Update(snippet.ID, form.Title, form.Content, form.Language, form.Expires, app.authenticatedUserID(r), form.TagList)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully updated!")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", snippet.ID), SEE_OTHER)
+}
+
+// diffContext is the number of unchanged lines shown around each change in a
+// diff.
+const diffContext = 3
+
+// The snippetHistory handler lists the revisions of a snippet, and shows the
+// differences between two of them: the two latest, unless others are chosen
+// with the from and to query string parameters.
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var form snippetHistoryForm
+
+	err := app.decodeQuery(r, &form)
+	if err != nil {
+		app.clientError(w, BAD_REQUEST)
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.IsOwner = app.isOwner(r, snippet)
+
+	data.Revisions, err = app.revisionAuthors(revisions)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if len(revisions) > 1 {
+		if form.To == 0 {
+			form.To = revisions[0].Version
+		}
+		if form.From == 0 {
+			form.From = max(1, form.To-1)
+		}
+
+		from, ok := findRevision(data.Revisions, form.From)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		to, ok := findRevision(data.Revisions, form.To)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		data.Diff = &revisionDiff{
+			From:  from,
+			To:    to,
+			Split: form.View == "split",
+			Hunks: diff.Hunks(diff.Lines(from.Content, to.Content), diffContext),
+		}
+	}
+
+	app.render(w, r, OK, "history.tmpl", data)
+}
+
+// The snippetRestorePost handler makes an earlier revision of a snippet
+// current again. The revision to restore is given by the version form field.
+func (app *application) snippetRestorePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, BAD_REQUEST)
+		return
+	}
+
+	version, err := strconv.Atoi(r.PostForm.Get("version"))
+	if err != nil || version < 1 {
+		app.clientError(w, BAD_REQUEST)
+		return
+	}
+
+	err = app.snippets.Restore(snippet.ID, version, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Revision %d successfully restored!", version))
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", snippet.ID), SEE_OTHER)
+}
+
+// The snippetDelete handler shows a confirmation page for deleting a snippet
+// with the delete key given in the "key" query string parameter.
+func (app *application) snippetDelete(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.IsOwner = app.isOwner(r, snippet)
+	data.DeleteKey = r.URL.Query().Get("key")
+
+	app.render(w, r, OK, "delete.tmpl", data)
+}
+
+func (app *application) snippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, BAD_REQUEST)
+		return
+	}
+
+	// The owner of a snippet can always delete it. Anyone else needs to
+	// present the delete key, either in the form body or the query string.
+	switch key := r.Form.Get("key"); {
+	case app.isOwner(r, snippet):
+		err = app.snippets.Delete(snippet.ID)
+	case key != "":
+		err = app.snippets.DeleteWithKey(snippet.ID, key)
+	default:
+		app.clientError(w, FORBIDDEN)
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			http.NotFound(w, r)
+		case errors.Is(err, models.ErrInvalidCredentials):
+			app.clientError(w, FORBIDDEN)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully deleted!")
+
+	http.Redirect(w, r, "/", SEE_OTHER)
+}
+
+func (app *application) userSignup(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = userSignupForm{}
+	app.render(w, r, OK, "signup.tmpl", data)
+}
+
+func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
+	// Declare an zero-valued instance of our userSignupForm struct.
+	var form userSignupForm
+
+	// Parse the form data into the userSignupForm struct.
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, BAD_REQUEST)
+		return
+	}
+
+	// Validate the form contents using our helper functions.
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.NotBlank(form.Email), "email", "This field cannot be blank")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "This field must be a valid email address")
+	form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank")
+	form.CheckField(validator.MinChars(form.Password, 8), "password", "This field must be at least 8 characters long")
+
+	// If there are any errors, redisplay the signup form along with a 422
+	// status code.
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, UNPROCESSABLE, "signup.tmpl", data)
+		return
+	}
+
+	// Try to create a new user record in the database. If the email already
+	// exists then add an error message to the form and re-display it.
+	err = app.users.Insert(form.Name, form.Email, form.Password)
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateEmail) {
+			form.AddFieldError("email", "Email address is already in use")
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, r, UNPROCESSABLE, "signup.tmpl", data)
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	// Otherwise add a confirmation flash message to the session confirming
+	// that their signup worked.
+	app.sessionManager.Put(r.Context(), "flash", "Your signup was successful. Please log in.")
+
+	// And redirect the user to the login page.
+	http.Redirect(w, r, "/user/login", SEE_OTHER)
+}
+
+func (app *application) userLogin(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = userLoginForm{}
+	app.render(w, r, OK, "login.tmpl", data)
+}
+
+func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
+	// Decode the form data into the userLoginForm struct.
+	var form userLoginForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, BAD_REQUEST)
+		return
+	}
+
+	// Do some validation checks on the form. We check that both email and
+	// password are provided, and also check the format of the email address as
+	// a UX-nicety (in case the user makes a typo).
+	form.CheckField(validator.NotBlank(form.Email), "email", "This field cannot be blank")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "This field must be a valid email address")
+	form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, UNPROCESSABLE, "login.tmpl", data)
+		return
+	}
+
+	// Check whether the credentials are valid. If they're not, add a generic
+	// non-field error message and re-display the login page.
+	id, err := app.users.Authenticate(form.Email, form.Password)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			form.AddNonFieldError("Email or password is incorrect")
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, r, UNPROCESSABLE, "login.tmpl", data)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// Use the RenewToken() method on the current session to change the session
+	// ID. It's good practice to generate a new session ID when the
+	// authentication state or privilege levels changes for the user (e.g. login
+	// and logout operations), to prevent session fixation attacks.
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Add the ID of the current user to the session, so that they are now
+	// 'logged in'.
+	app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
+
+	// Redirect the user to the create snippet page.
+	http.Redirect(w, r, "/snippet/create", SEE_OTHER)
+}
+
+func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
+	// Use the RenewToken() method on the current session to change the session
+	// ID again.
+	err := app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Remove the authenticatedUserID from the session data so that the user is
+	// 'logged out'.
+	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+
+	// Add a flash message to the session to confirm to the user that they've
+	// been logged out.
+	app.sessionManager.Put(r.Context(), "flash", "You've been logged out successfully!")
+
+	// Redirect the user to the application home page.
+	http.Redirect(w, r, "/", SEE_OTHER)
+}
+
+// The accountTokens handler shows the authenticated user's API tokens, along
+// with a form for creating a new one.
+func (app *application) accountTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := app.tokens.List(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Tokens = tokens
+	data.Form = tokenCreateForm{Scope: models.ScopeWrite}
+	// A newly created token is shown once, straight after it is created.
+	data.NewToken = app.sessionManager.PopString(r.Context(), "newToken")
+
+	app.render(w, r, OK, "tokens.tmpl", data)
+}
+
+func (app *application) accountTokensPost(w http.ResponseWriter, r *http.Request) {
+	var form tokenCreateForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, BAD_REQUEST)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 100), "name", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.PermittedValue(form.Scope, models.ScopeRead, models.ScopeWrite), "scope", "This field must be equal to read or write")
+
+	userID := app.authenticatedUserID(r)
+
+	if !form.Valid() {
+		tokens, err := app.tokens.List(userID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		data := app.newTemplateData(r)
+		data.Tokens = tokens
+		data.Form = form
+		app.render(w, r, UNPROCESSABLE, "tokens.tmpl", data)
+		return
+	}
+
+	token, err := app.tokens.Insert(userID, form.Name, form.Scope)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "newToken", token)
+
+	http.Redirect(w, r, "/account/tokens", SEE_OTHER)
+}
+
+func (app *application) accountTokenRevokePost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = app.tokens.Revoke(id, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Token successfully revoked!")
+
+	http.Redirect(w, r, "/account/tokens", SEE_OTHER)
+}
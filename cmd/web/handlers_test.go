@@ -0,0 +1,1038 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Galbeyte1/snippetbox/internal/models"
+)
+
+func TestSnippetView(t *testing.T) {
+	app := newTestApplication(t)
+
+	id, _, err := app.snippets.Insert("An old silent pond", "An old silent pond...", "", 7, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiredID, _, err := app.snippets.Insert("Expired", "Gone", "", 0, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goID, _, err := app.snippets.Insert("Hello", "package main\n", "go", 7, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{"Valid ID", "/snippet/view/" + strconv.Itoa(id), http.StatusOK, "An old silent pond..."},
+		{"Highlighted", "/snippet/view/" + strconv.Itoa(goID), http.StatusOK, "Language: Go"},
+		{"Non-existent ID", "/snippet/view/99", http.StatusNotFound, ""},
+		{"Expired ID", "/snippet/view/" + strconv.Itoa(expiredID), http.StatusNotFound, ""},
+		{"Negative ID", "/snippet/view/-1", http.StatusNotFound, ""},
+		{"Decimal ID", "/snippet/view/1.23", http.StatusNotFound, ""},
+		{"String ID", "/snippet/view/foo", http.StatusNotFound, ""},
+		{"Empty ID", "/snippet/view/", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("got body %q; want it to contain %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetCreatePost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	_, _, body := ts.get(t, "/snippet/create")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name      string
+		title     string
+		content   string
+		expires   string
+		csrfToken string
+		wantCode  int
+	}{
+		{"Valid submission", "O snail", "Climb Mount Fuji", "7", csrfToken, http.StatusSeeOther},
+		{"Blank title", "", "Climb Mount Fuji", "7", csrfToken, http.StatusUnprocessableEntity},
+		{"Blank content", "O snail", "", "7", csrfToken, http.StatusUnprocessableEntity},
+		{"Invalid expiry", "O snail", "Climb Mount Fuji", "0", csrfToken, http.StatusUnprocessableEntity},
+		{"Invalid CSRF token", "O snail", "Climb Mount Fuji", "7", "wrongToken", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", tt.title)
+			form.Add("content", tt.content)
+			form.Add("expires", tt.expires)
+			form.Add("csrf_token", tt.csrfToken)
+
+			code, header, _ := ts.postForm(t, "/snippet/create", form)
+
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			if code != http.StatusSeeOther {
+				return
+			}
+
+			// The new snippet's page shows its delete key exactly once.
+			location := header.Get("Location")
+
+			_, _, body := ts.get(t, location)
+			if !strings.Contains(body, "Climb Mount Fuji") || !strings.Contains(body, "?key=") {
+				t.Errorf("got body %q; want the snippet and its delete key", body)
+			}
+
+			_, _, body = ts.get(t, location)
+			if strings.Contains(body, "?key=") {
+				t.Error("delete key shown a second time")
+			}
+		})
+	}
+}
+
+func TestSnippetFork(t *testing.T) {
+	app := newTestApplication(t)
+
+	parentID, _, err := app.snippets.Insert("Original", "Original content", "go", 7, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	code, _, body := ts.get(t, "/snippet/fork/"+strconv.Itoa(parentID))
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+
+	for _, want := range []string{`value="Original"`, "Original content", `name="parent_id" value="` + strconv.Itoa(parentID) + `"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("got fork form %q; want it to contain %q", body, want)
+		}
+	}
+
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name       string
+		parentID   string
+		wantParent int
+	}{
+		{"Existing parent", strconv.Itoa(parentID), parentID},
+		{"Missing parent", "99", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", "Fork")
+			form.Add("content", "Forked content")
+			form.Add("expires", "7")
+			form.Add("parent_id", tt.parentID)
+			form.Add("csrf_token", csrfToken)
+
+			code, header, _ := ts.postForm(t, "/snippet/create", form)
+			if code != http.StatusSeeOther {
+				t.Fatalf("got status %d; want %d", code, http.StatusSeeOther)
+			}
+
+			location := header.Get("Location")
+			id := strings.TrimPrefix(location, "/snippet/view/")
+
+			forkID, err := strconv.Atoi(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fork, err := app.snippets.Get(forkID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if fork.ParentID != tt.wantParent {
+				t.Errorf("got parent %d; want %d", fork.ParentID, tt.wantParent)
+			}
+
+			_, _, body := ts.get(t, location)
+			if got := strings.Contains(body, "Forked from"); got != (tt.wantParent != 0) {
+				t.Errorf("got %t for showing the parent; want %t", got, tt.wantParent != 0)
+			}
+		})
+	}
+
+	_, _, body = ts.get(t, "/snippet/view/"+strconv.Itoa(parentID))
+	if !strings.Contains(body, "<h3>Forks</h3>") {
+		t.Error("forks not listed on the parent's page")
+	}
+}
+
+func TestSnippetDeleteWithKey(t *testing.T) {
+	app := newTestApplication(t)
+
+	id, key, err := app.snippets.Insert("Doomed", "content", "", 7, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	_, _, body := ts.get(t, "/snippet/delete/"+strconv.Itoa(id)+"?key="+key)
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name     string
+		key      string
+		wantCode int
+	}{
+		{"No key", "", http.StatusForbidden},
+		{"Wrong key", "wrong", http.StatusForbidden},
+		{"Right key", key, http.StatusSeeOther},
+		{"Already deleted", key, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("key", tt.key)
+			form.Add("csrf_token", csrfToken)
+
+			code, _, _ := ts.postForm(t, "/snippet/delete/"+strconv.Itoa(id), form)
+
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+		})
+	}
+
+	_, err = app.snippets.Get(id)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("got error %v; want the snippet deleted", err)
+	}
+}
+
+func TestTagView(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, _, err := app.snippets.Insert("Tagged", "content", "", 7, 0, 0, []string{"haiku"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = app.snippets.Insert("Untagged", "content", "", 7, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	code, _, body := ts.get(t, "/tag/haiku")
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+
+	if !strings.Contains(body, "Tagged") || strings.Contains(body, "Untagged") {
+		t.Errorf("got body %q; want only the tagged snippet", body)
+	}
+
+	code, _, _ = ts.get(t, "/tag/not%20a%20tag")
+	if code != http.StatusNotFound {
+		t.Errorf("got status %d for an invalid tag; want %d", code, http.StatusNotFound)
+	}
+}
+
+var nextPageRX = regexp.MustCompile(`href="(/snippets\?[^"]*after=[^"]*)"`)
+
+func TestSnippetList(t *testing.T) {
+	app := newTestApplication(t)
+
+	for i := range models.ListPageSize + 1 {
+		_, _, err := app.snippets.Insert("Snippet "+strconv.Itoa(i), "content", "", 7, 0, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+	}{
+		{"Default sort", "/snippets", http.StatusOK},
+		{"Title sort", "/snippets?sort=title", http.StatusOK},
+		{"Unknown sort", "/snippets?sort=random", http.StatusBadRequest},
+		{"Invalid cursor", "/snippets?after=garbage", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _ := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+		})
+	}
+
+	// The first page has the newest snippets, and links to the second, which
+	// has the oldest.
+	_, _, body := ts.get(t, "/snippets")
+	if !strings.Contains(body, "Snippet "+strconv.Itoa(models.ListPageSize)) || strings.Contains(body, "Snippet 0<") {
+		t.Errorf("got first page %q; want the newest snippets", body)
+	}
+
+	matches := nextPageRX.FindStringSubmatch(body)
+	if len(matches) < 2 {
+		t.Fatal("no link to the next page")
+	}
+
+	code, _, body := ts.get(t, html.UnescapeString(matches[1]))
+	if code != http.StatusOK || !strings.Contains(body, "Snippet 0<") {
+		t.Errorf("got status %d and second page %q; want the oldest snippet", code, body)
+	}
+}
+
+func TestSnippetRaw(t *testing.T) {
+	app := newTestApplication(t)
+
+	id, _, err := app.snippets.Insert("Raw: Example!", "line one\nline two\n", "go", 7, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	code, header, body := ts.get(t, "/snippet/raw/"+strconv.Itoa(id))
+
+	if code != http.StatusOK {
+		t.Errorf("got status %d; want %d", code, http.StatusOK)
+	}
+
+	if got := header.Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+		t.Errorf("got Content-Type %q; want text/plain", got)
+	}
+
+	if body != "line one\nline two\n" {
+		t.Errorf("got body %q; want the content unchanged", body)
+	}
+
+	// A client with an up to date copy gets a 304 Not Modified response.
+	code, _, _ = ts.do(t, http.MethodGet, "/snippet/raw/"+strconv.Itoa(id), http.Header{"If-None-Match": {header.Get("ETag")}}, nil)
+	if code != http.StatusNotModified {
+		t.Errorf("got status %d for a matching ETag; want %d", code, http.StatusNotModified)
+	}
+
+	code, header, _ = ts.get(t, "/snippet/download/"+strconv.Itoa(id))
+	if code != http.StatusOK {
+		t.Errorf("got status %d downloading; want %d", code, http.StatusOK)
+	}
+
+	if got, want := header.Get("Content-Disposition"), `attachment; filename=raw-example.go`; got != want {
+		t.Errorf("got Content-Disposition %q; want %q", got, want)
+	}
+
+	code, _, _ = ts.get(t, "/snippet/raw/99")
+	if code != http.StatusNotFound {
+		t.Errorf("got status %d for a missing snippet; want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestSnippetSearch(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, _, err := app.snippets.Insert("Zebra crossing", "Look both ways", "", 7, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{"Empty form", "/snippet/search", http.StatusOK, ""},
+		{"Match", "/snippet/search?q=zebra", http.StatusOK, "<mark>Zebra</mark> crossing"},
+		{"Blank query", "/snippet/search?q=", http.StatusUnprocessableEntity, "This field cannot be blank"},
+		{"API match", "/api/v1/snippets/search?q=zebra", http.StatusOK, `"title": "Zebra crossing"`},
+		{"API no match", "/api/v1/snippets/search?q=giraffe", http.StatusOK, `"snippets": []`},
+		{"API invalid page", "/api/v1/snippets/search?q=zebra&page=x", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("got body %q; want it to contain %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestUserSignupPost(t *testing.T) {
+	app := newTestApplication(t)
+	newTestUser(t, app, "taken@example.com")
+
+	ts := newTestServer(t, app.routes())
+
+	_, _, body := ts.get(t, "/user/signup")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantCode int
+		wantBody string
+	}{
+		{"Valid submission", "bob@example.com", "validPa$$word", http.StatusSeeOther, ""},
+		{"Invalid email", "bob@example.", "validPa$$word", http.StatusUnprocessableEntity, "This field must be a valid email address"},
+		{"Short password", "bob@example.com", "pa$$", http.StatusUnprocessableEntity, "This field must be at least 8 characters long"},
+		{"Duplicate email", "taken@example.com", "validPa$$word", http.StatusUnprocessableEntity, "Email address is already in use"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("name", "Bob")
+			form.Add("email", tt.email)
+			form.Add("password", tt.password)
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, "/user/signup", form)
+
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("got body %q; want it to contain %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestUserLoginPost(t *testing.T) {
+	app := newTestApplication(t)
+	newTestUser(t, app, "alice@example.com")
+
+	ts := newTestServer(t, app.routes())
+
+	_, _, body := ts.get(t, "/user/login")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantCode int
+	}{
+		{"Unknown email", "bob@example.com", "pa$$word", http.StatusUnprocessableEntity},
+		{"Wrong password", "alice@example.com", "wrong", http.StatusUnprocessableEntity},
+		{"Valid credentials", "alice@example.com", "pa$$word", http.StatusSeeOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("email", tt.email)
+			form.Add("password", tt.password)
+			form.Add("csrf_token", csrfToken)
+
+			code, _, _ := ts.postForm(t, "/user/login", form)
+
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+		})
+	}
+
+	// The session is renewed on login, so protected pages are now available.
+	code, _, _ := ts.get(t, "/account/tokens")
+	if code != http.StatusOK {
+		t.Errorf("got status %d after logging in; want %d", code, http.StatusOK)
+	}
+}
+
+var keepExpiryRX = regexp.MustCompile(`name="expires"\s+value="0"\s+checked`)
+
+func TestSnippetEdit(t *testing.T) {
+	app := newTestApplication(t)
+	ownerID := newTestUser(t, app, "alice@example.com")
+	newTestUser(t, app, "bob@example.com")
+
+	id, _, err := app.snippets.Insert("Original", "Original content", "", 7, ownerID, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	editPath := "/snippet/edit/" + strconv.Itoa(id)
+
+	anonymous := newTestServer(t, app.routes())
+
+	code, header, _ := anonymous.get(t, editPath)
+	if code != http.StatusSeeOther || header.Get("Location") != "/user/login" {
+		t.Errorf("got status %d to %q; want a redirect to the login page", code, header.Get("Location"))
+	}
+
+	other := newTestServer(t, app.routes())
+	other.login(t, "bob@example.com")
+
+	code, _, _ = other.get(t, editPath)
+	if code != http.StatusForbidden {
+		t.Errorf("got status %d for another user; want %d", code, http.StatusForbidden)
+	}
+
+	owner := newTestServer(t, app.routes())
+	owner.login(t, "alice@example.com")
+
+	code, _, body := owner.get(t, editPath)
+	if code != http.StatusOK {
+		t.Fatalf("got status %d for the owner; want %d", code, http.StatusOK)
+	}
+
+	// The form keeps the current expiry unless another one is chosen.
+	if !keepExpiryRX.MatchString(body) {
+		t.Error("want the keep current expiry option checked")
+	}
+
+	original, err := app.snippets.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{}
+	form.Add("title", "Edited")
+	form.Add("content", "Edited content")
+	form.Add("expires", "0")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ = owner.postForm(t, editPath, form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("got status %d saving the edit; want %d", code, http.StatusSeeOther)
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snippet.Title != "Edited" || snippet.Content != "Edited content" {
+		t.Errorf("got snippet %+v; want the edited title and content", snippet)
+	}
+
+	if !snippet.Expires.Equal(original.Expires) {
+		t.Errorf("got expiry %s; want it kept at %s", snippet.Expires, original.Expires)
+	}
+}
+
+func TestSnippetHistory(t *testing.T) {
+	app := newTestApplication(t)
+	ownerID := newTestUser(t, app, "alice@example.com")
+
+	id, _, err := app.snippets.Insert("Original", "Original content", "", 7, ownerID, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.snippets.Update(id, "Edited", "Edited content", "", 7, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	historyPath := "/snippet/view/" + strconv.Itoa(id) + "/history"
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []string
+	}{
+		{
+			"Latest changes",
+			historyPath,
+			http.StatusOK,
+			[]string{
+				"<td>Test User</td>",
+				"<td>Anonymous</td>",
+				"Title changed from “Original” to “Edited”.",
+				`<td class="delete">-<span class="change">Original</span> content</td>`,
+				`<td class="insert">&#43;<span class="change">Edited</span> content</td>`,
+			},
+		},
+		{"Same version", historyPath + "?from=2&to=2", http.StatusOK, []string{"The content is the same."}},
+		{"Missing version", historyPath + "?from=9", http.StatusNotFound, nil},
+		{"Missing snippet", "/snippet/view/99/history", http.StatusNotFound, nil},
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			for _, want := range tt.wantBody {
+				if !strings.Contains(body, want) {
+					t.Errorf("want body to contain %q", want)
+				}
+			}
+
+			// Only the owner is offered the restore button.
+			if strings.Contains(body, "<button>Restore</button>") {
+				t.Error("got a restore button for an anonymous user")
+			}
+		})
+	}
+}
+
+func TestSnippetDiff(t *testing.T) {
+	app := newTestApplication(t)
+
+	a, _, err := app.snippets.Insert("First", "one\ntwo\nthree\n", "go", 7, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _, err := app.snippets.Insert("Second", "one\n2\nthree\n", "go", 7, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diffPath := fmt.Sprintf("/snippet/diff/%d/%d", a, b)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{"Unified", diffPath, http.StatusOK, `<td class="insert">&#43;2</td>`},
+		{"Split", diffPath + "?view=split", http.StatusOK, `<td class="insert">2</td>`},
+		{"Same snippet", fmt.Sprintf("/snippet/diff/%d/%d", a, a), http.StatusOK, "The content is the same."},
+		{"Missing snippet", fmt.Sprintf("/snippet/diff/%d/99", a), http.StatusNotFound, ""},
+		{
+			"Patch",
+			diffPath + "/patch",
+			http.StatusOK,
+			"--- a/first.go\n+++ b/second.go\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetRestorePost(t *testing.T) {
+	app := newTestApplication(t)
+	ownerID := newTestUser(t, app, "alice@example.com")
+	newTestUser(t, app, "bob@example.com")
+
+	id, _, err := app.snippets.Insert("Original", "Original content", "", 7, ownerID, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.snippets.Update(id, "Edited", "Edited content", "", 7, ownerID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	historyPath := "/snippet/view/" + strconv.Itoa(id) + "/history"
+	restorePath := "/snippet/restore/" + strconv.Itoa(id)
+
+	other := newTestServer(t, app.routes())
+	other.login(t, "bob@example.com")
+
+	_, _, body := other.get(t, historyPath)
+
+	form := url.Values{}
+	form.Add("version", "1")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ := other.postForm(t, restorePath, form)
+	if code != http.StatusForbidden {
+		t.Errorf("got status %d for another user; want %d", code, http.StatusForbidden)
+	}
+
+	owner := newTestServer(t, app.routes())
+	owner.login(t, "alice@example.com")
+
+	_, _, body = owner.get(t, historyPath)
+	if !strings.Contains(body, "<button>Restore</button>") {
+		t.Error("want a restore button for the owner")
+	}
+
+	csrfToken := extractCSRFToken(t, body)
+
+	form = url.Values{}
+	form.Add("version", "9")
+	form.Add("csrf_token", csrfToken)
+
+	code, _, _ = owner.postForm(t, restorePath, form)
+	if code != http.StatusNotFound {
+		t.Errorf("got status %d for a missing version; want %d", code, http.StatusNotFound)
+	}
+
+	form.Set("version", "1")
+
+	code, header, _ := owner.postForm(t, restorePath, form)
+	if code != http.StatusSeeOther || header.Get("Location") != "/snippet/view/"+strconv.Itoa(id) {
+		t.Fatalf("got status %d to %q; want a redirect to the snippet", code, header.Get("Location"))
+	}
+
+	_, _, body = owner.get(t, header.Get("Location"))
+	if !strings.Contains(body, "Revision 1 successfully restored!") {
+		t.Error("want the restored flash message")
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snippet.Title != "Original" || snippet.Content != "Original content" {
+		t.Errorf("got snippet %+v; want the original title and content", snippet)
+	}
+}
+
+func TestSnippetDeleteByOwner(t *testing.T) {
+	app := newTestApplication(t)
+	ownerID := newTestUser(t, app, "alice@example.com")
+	newTestUser(t, app, "bob@example.com")
+
+	id, _, err := app.snippets.Insert("Doomed", "content", "", 7, ownerID, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deletePath := "/snippet/delete/" + strconv.Itoa(id)
+
+	// Without the delete key, nobody but the owner may delete the snippet.
+	other := newTestServer(t, app.routes())
+	other.login(t, "bob@example.com")
+
+	_, _, body := other.get(t, deletePath)
+
+	form := url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ := other.postForm(t, deletePath, form)
+	if code != http.StatusForbidden {
+		t.Errorf("got status %d for another user; want %d", code, http.StatusForbidden)
+	}
+
+	owner := newTestServer(t, app.routes())
+	owner.login(t, "alice@example.com")
+
+	_, _, body = owner.get(t, deletePath)
+
+	form = url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ = owner.postForm(t, deletePath, form)
+	if code != http.StatusSeeOther {
+		t.Errorf("got status %d for the owner; want %d", code, http.StatusSeeOther)
+	}
+
+	_, err = app.snippets.Get(id)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("got error %v; want the snippet deleted", err)
+	}
+}
+
+var newTokenRX = regexp.MustCompile(`<code>(.+)</code>`)
+
+func TestAccountTokens(t *testing.T) {
+	app := newTestApplication(t)
+	userID := newTestUser(t, app, "alice@example.com")
+
+	ts := newTestServer(t, app.routes())
+	ts.login(t, "alice@example.com")
+
+	_, _, body := ts.get(t, "/account/tokens")
+	csrfToken := extractCSRFToken(t, body)
+
+	form := url.Values{}
+	form.Add("name", "laptop")
+	form.Add("scope", "admin")
+	form.Add("csrf_token", csrfToken)
+
+	code, _, _ := ts.postForm(t, "/account/tokens", form)
+	if code != http.StatusUnprocessableEntity {
+		t.Errorf("got status %d for an unknown scope; want %d", code, http.StatusUnprocessableEntity)
+	}
+
+	form.Set("scope", models.ScopeWrite)
+
+	code, _, _ = ts.postForm(t, "/account/tokens", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("got status %d creating a token; want %d", code, http.StatusSeeOther)
+	}
+
+	// The new token is shown once, straight after it is created.
+	_, _, body = ts.get(t, "/account/tokens")
+
+	matches := newTokenRX.FindStringSubmatch(body)
+	if len(matches) < 2 {
+		t.Fatal("new token not shown")
+	}
+	token := matches[1]
+
+	_, _, body = ts.get(t, "/account/tokens")
+	if strings.Contains(body, token) {
+		t.Error("token shown a second time")
+	}
+
+	code, header, _ := ts.postJSON(t, "/api/v1/snippets", token, `{"title": "O snail", "content": "Climb Mount Fuji", "expires": 7}`)
+	if code != http.StatusCreated {
+		t.Fatalf("got status %d creating a snippet with the token; want %d", code, http.StatusCreated)
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(header.Get("Location"), "/api/v1/snippets/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snippet.UserID != userID {
+		t.Errorf("got snippet owned by %d; want %d", snippet.UserID, userID)
+	}
+
+	tokens, err := app.tokens.List(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 1 || tokens[0].LastUsed == nil {
+		t.Fatalf("got tokens %+v; want one token marked as used", tokens)
+	}
+
+	form = url.Values{}
+	form.Add("csrf_token", csrfToken)
+
+	code, _, _ = ts.postForm(t, "/account/tokens/"+strconv.Itoa(tokens[0].ID)+"/revoke", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("got status %d revoking the token; want %d", code, http.StatusSeeOther)
+	}
+
+	code, _, _ = ts.postJSON(t, "/api/v1/snippets", token, `{"title": "O snail", "content": "Climb Mount Fuji", "expires": 7}`)
+	if code != http.StatusUnauthorized {
+		t.Errorf("got status %d with a revoked token; want %d", code, http.StatusUnauthorized)
+	}
+}
+
+func TestReadTokenCreate(t *testing.T) {
+	app := newTestApplication(t)
+	userID := newTestUser(t, app, "alice@example.com")
+
+	token, err := app.tokens.Insert(userID, "read", models.ScopeRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	// A read token can't create snippets, through the API or by pasting.
+	code, _, body := ts.postJSON(t, "/api/v1/snippets", token, `{"title": "O snail", "content": "Climb Mount Fuji", "expires": 7}`)
+	if code != http.StatusForbidden || !strings.Contains(body, `"message": "this token does not have the write scope"`) {
+		t.Errorf("got status %d and body %q from the API; want %d", code, body, http.StatusForbidden)
+	}
+
+	code, _, body = ts.do(t, http.MethodPost, "/paste", http.Header{"Authorization": {"Bearer " + token}}, []byte("O snail"))
+	if code != http.StatusForbidden || body != "this token does not have the write scope\n" {
+		t.Errorf("got status %d and body %q from paste; want %d", code, body, http.StatusForbidden)
+	}
+
+	page, err := app.snippets.List(models.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Snippets) != 0 {
+		t.Errorf("got %d snippets; want none created", len(page.Snippets))
+	}
+}
+
+func TestAPISnippetDelete(t *testing.T) {
+	app := newTestApplication(t)
+	ownerID := newTestUser(t, app, "alice@example.com")
+	otherID := newTestUser(t, app, "bob@example.com")
+
+	writeToken, err := app.tokens.Insert(ownerID, "write", models.ScopeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readToken, err := app.tokens.Insert(ownerID, "read", models.ScopeRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherToken, err := app.tokens.Insert(otherID, "other", models.ScopeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	tests := []struct {
+		name     string
+		token    string
+		key      string
+		wantCode int
+	}{
+		{"Owner with write token", writeToken, "", http.StatusOK},
+		{"Owner with read token", readToken, "", http.StatusForbidden},
+		{"Another user", otherToken, "", http.StatusForbidden},
+		{"Anonymous", "", "", http.StatusForbidden},
+		{"Wrong key", "", "wrong", http.StatusForbidden},
+		{"Right key", "", "right", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, key, err := app.snippets.Insert("Doomed", "content", "", 7, ownerID, 0, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			header := http.Header{}
+			if tt.token != "" {
+				header.Set("Authorization", "Bearer "+tt.token)
+			}
+			switch tt.key {
+			case "right":
+				header.Set("X-Delete-Key", key)
+			case "wrong":
+				header.Set("X-Delete-Key", "wrong"+key)
+			}
+
+			code, _, body := ts.do(t, http.MethodDelete, "/api/v1/snippets/"+strconv.Itoa(id), header, nil)
+
+			if code != tt.wantCode {
+				t.Errorf("got status %d with body %q; want %d", code, body, tt.wantCode)
+			}
+
+			_, err = app.snippets.Get(id)
+			if deleted := errors.Is(err, models.ErrNoRecord); deleted != (tt.wantCode == http.StatusOK) {
+				t.Errorf("got deleted %t; want %t", deleted, tt.wantCode == http.StatusOK)
+			}
+		})
+	}
+
+	code, _, _ := ts.do(t, http.MethodDelete, "/api/v1/snippets/99", http.Header{"Authorization": {"Bearer " + writeToken}}, nil)
+	if code != http.StatusNotFound {
+		t.Errorf("got status %d for a missing snippet; want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestAPISnippets(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"Valid snippet", `{"title": "O snail", "content": "Climb Mount Fuji", "expires": 7, "tags": ["haiku"]}`, http.StatusCreated},
+		{"Invalid tag", `{"title": "O snail", "content": "Climb Mount Fuji", "expires": 7, "tags": ["no spaces"]}`, http.StatusUnprocessableEntity},
+		{"Invalid expiry", `{"title": "O snail", "content": "Climb Mount Fuji", "expires": 2}`, http.StatusUnprocessableEntity},
+		{"Malformed JSON", `{"title": `, http.StatusBadRequest},
+		{"Unknown field", `{"title": "O snail", "author": "Issa"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, _ := ts.postJSON(t, "/api/v1/snippets", "", tt.body)
+
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			if code != http.StatusCreated {
+				return
+			}
+
+			code, _, body := ts.get(t, header.Get("Location"))
+			if code != http.StatusOK {
+				t.Fatalf("got status %d fetching the snippet; want %d", code, http.StatusOK)
+			}
+
+			var response struct {
+				Snippet models.Snippet `json:"snippet"`
+			}
+
+			err := json.Unmarshal([]byte(body), &response)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if response.Snippet.Title != "O snail" || response.Snippet.Content != "Climb Mount Fuji" || !slices.Equal(response.Snippet.Tags, []string{"haiku"}) {
+				t.Errorf("got snippet %+v; want the one created", response.Snippet)
+			}
+		})
+	}
+}
@@ -2,10 +2,21 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
+	"github.com/Galbeyte1/snippetbox/internal/highlight"
+	"github.com/Galbeyte1/snippetbox/internal/models"
 	"github.com/go-playground/form/v4"
 )
 
@@ -44,13 +55,30 @@ func (app *application) render(w http.ResponseWriter, r *http.Request, status in
 
 }
 
+// The logURI helper returns the request URI for log entries, with the value of
+// the key query string parameter redacted. That is a snippet's plain-text
+// delete key, which would otherwise end up in the logs.
+func logURI(r *http.Request) string {
+	query := r.URL.Query()
+	if !query.Has("key") {
+		return r.URL.RequestURI()
+	}
+
+	query.Set("key", "REDACTED")
+
+	u := *r.URL
+	u.RawQuery = query.Encode()
+
+	return u.RequestURI()
+}
+
 // The serverError helper writes a log entry at error level (including the request
 // method and URI as attributes), then sends a generic 500 Internal Server Error
 // response to the user.
 func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
 	var (
 		method = r.Method
-		uri    = r.URL.RequestURI()
+		uri    = logURI(r)
 		// Use debug.Stack() to get the stack trace outlining the execution path of
 		// the application for the current goroutine. This returns a byte slice, which
 		// we need to convert to a string so that it's readable in the log entry.
@@ -91,3 +119,322 @@ func (app *application) decodePostForm(r *http.Request, dst any) error {
 
 	return nil
 }
+
+// splitTags splits the tags typed into the HTML form, which may be separated
+// by spaces or commas.
+func splitTags(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// normalizeTags lowercases the tags, then sorts them and removes duplicates,
+// so that "Go, go" and "go" give the same result.
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+
+	slices.Sort(normalized)
+
+	return slices.Compact(normalized)
+}
+
+// The decodeQuery helper works like decodePostForm, but decodes the URL query
+// string parameters of a GET request instead.
+func (app *application) decodeQuery(r *http.Request, dst any) error {
+	err := app.formDecoder.Decode(dst, r.URL.Query())
+	if err != nil {
+		var invalidDecoderError *form.InvalidDecoderError
+
+		if errors.As(err, &invalidDecoderError) {
+			panic(err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Return true if the current request is from an authenticated user, otherwise
+// return false.
+func (app *application) isAuthenticated(r *http.Request) bool {
+	isAuthenticated, ok := r.Context().Value(isAuthenticatedContextKey).(bool)
+	if !ok {
+		return false
+	}
+
+	return isAuthenticated
+}
+
+// Return the ID of the authenticated user for the current request, or 0 if the
+// request is anonymous.
+func (app *application) authenticatedUserID(r *http.Request) int {
+	if !app.isAuthenticated(r) {
+		return 0
+	}
+
+	return app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+}
+
+// Return true if the snippet was created by the authenticated user making the
+// current request. Anonymous snippets have no owner.
+func (app *application) isOwner(r *http.Request, snippet models.Snippet) bool {
+	return snippet.UserID != 0 && snippet.UserID == app.authenticatedUserID(r)
+}
+
+// The snippetFromPath helper fetches the snippet identified by the {id} path
+// value. If the ID is invalid or there is no such snippet, it sends the
+// appropriate error response and returns false.
+func (app *application) snippetFromPath(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+	return app.snippetFromPathValue(w, r, "id")
+}
+
+// The snippetFromPathValue helper works like snippetFromPath, for routes
+// where the snippet ID has another name.
+func (app *application) snippetFromPathValue(w http.ResponseWriter, r *http.Request, name string) (models.Snippet, bool) {
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return models.Snippet{}, false
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return models.Snippet{}, false
+	}
+
+	return snippet, true
+}
+
+// serveSnippetContent writes the content of a snippet as plain text. Snippets
+// can be edited, so we don't let clients reuse a cached copy without asking.
+// Instead we send an ETag derived from the content, which http.ServeContent()
+// compares with the If-None-Match header to send a 304 Not Modified response
+// when it hasn't changed. ServeContent() also handles HEAD and Range requests.
+func (app *application) serveSnippetContent(w http.ResponseWriter, r *http.Request, snippet models.Snippet) {
+	sum := sha256.Sum256([]byte(snippet.Content))
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+
+	http.ServeContent(w, r, "", time.Time{}, strings.NewReader(snippet.Content))
+}
+
+// snippetFilename returns the file name used when downloading a snippet: its
+// title in lowercase with runs of other characters than letters and digits
+// replaced by hyphens, followed by the extension for its language.
+func snippetFilename(snippet models.Snippet) string {
+	var b strings.Builder
+
+	hyphen := false
+	for _, r := range strings.ToLower(snippet.Title) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+
+		if b.Len() >= 50 {
+			break
+		}
+	}
+
+	name := b.String()
+	if name == "" {
+		name = fmt.Sprintf("snippet-%d", snippet.ID)
+	}
+
+	return name + highlight.Resolve(snippet.Content, snippet.Language).Extension
+}
+
+// The ownedSnippet helper fetches the snippet identified by the {id} path
+// value and checks that the current user is allowed to modify it. If not, it
+// sends the appropriate error response and returns false.
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return models.Snippet{}, false
+	}
+
+	if !app.isOwner(r, snippet) {
+		app.clientError(w, FORBIDDEN)
+		return models.Snippet{}, false
+	}
+
+	return snippet, true
+}
+
+// The revisionAuthors helper adds the names of their authors to a snippet's
+// revisions, looking up each author only once. The author of an anonymous
+// revision, or one whose account has been deleted, is left empty.
+func (app *application) revisionAuthors(revisions []models.Revision) ([]revision, error) {
+	names := make(map[int]string)
+	result := make([]revision, len(revisions))
+
+	for i, rev := range revisions {
+		name, ok := names[rev.UserID]
+
+		if !ok && rev.UserID != 0 {
+			user, err := app.users.Get(rev.UserID)
+			if err != nil && !errors.Is(err, models.ErrNoRecord) {
+				return nil, err
+			}
+
+			name = user.Name
+			names[rev.UserID] = name
+		}
+
+		result[i] = revision{Revision: rev, Author: name}
+	}
+
+	return result, nil
+}
+
+// findRevision returns the revision with the given version number, and
+// whether there is one.
+func findRevision(revisions []revision, version int) (revision, bool) {
+	for _, rev := range revisions {
+		if rev.Version == version {
+			return rev, true
+		}
+	}
+
+	return revision{}, false
+}
+
+// Define an envelope type for the top-level JSON objects sent by the API.
+type envelope map[string]any
+
+// The writeJSON helper encodes data as JSON and sends it with the given status
+// code and any additional headers.
+func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
+	js, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	// Append a newline to make it easier to view in terminal applications.
+	js = append(js, '\n')
+
+	for key, value := range headers {
+		w.Header()[key] = value
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+
+	return nil
+}
+
+// The readJSON helper decodes a single JSON value from the request body into
+// dst. Unknown fields, trailing data and bodies over 1MB are rejected with an
+// error message that is safe to send back to the client.
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1_048_576)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var unmarshalTypeError *json.UnmarshalTypeError
+		var invalidUnmarshalError *json.InvalidUnmarshalError
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("body contains badly-formed JSON")
+		case errors.As(err, &unmarshalTypeError):
+			if unmarshalTypeError.Field != "" {
+				return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+			}
+			return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+		case errors.Is(err, io.EOF):
+			return errors.New("body must not be empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+			return fmt.Errorf("body contains unknown key %s", fieldName)
+		case errors.As(err, &maxBytesError):
+			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		// An invalid destination is a bug in our code rather than a client
+		// error, so we panic like decodePostForm does.
+		case errors.As(err, &invalidUnmarshalError):
+			panic(err)
+		default:
+			return err
+		}
+	}
+
+	err = dec.Decode(&struct{}{})
+	if !errors.Is(err, io.EOF) {
+		return errors.New("body must only contain a single JSON value")
+	}
+
+	return nil
+}
+
+// The errorJSON helper sends a structured JSON error body with the given
+// status code, in place of the plain-text http.Error response.
+func (app *application) errorJSON(w http.ResponseWriter, r *http.Request, status int, message string) {
+	env := envelope{"error": envelope{"status": status, "message": message}}
+
+	err := app.writeJSON(w, status, env, nil)
+	if err != nil {
+		app.logger.Error(err.Error(), "method", r.Method, "uri", logURI(r))
+		w.WriteHeader(SERVER_ERROR)
+	}
+}
+
+// The serverErrorJSON helper is the JSON equivalent of serverError.
+func (app *application) serverErrorJSON(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Error(err.Error(), "method", r.Method, "uri", logURI(r))
+	app.errorJSON(w, r, SERVER_ERROR, http.StatusText(SERVER_ERROR))
+}
+
+// The invalidTokenJSON helper sends a 401 response for a missing or unknown
+// API token.
+func (app *application) invalidTokenJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	app.errorJSON(w, r, http.StatusUnauthorized, "invalid or missing authentication token")
+}
+
+// The failedValidationJSON helper sends a 422 response including the
+// validation error message for each form field.
+func (app *application) failedValidationJSON(w http.ResponseWriter, r *http.Request, fieldErrors map[string]string) {
+	env := envelope{"error": envelope{
+		"status":       UNPROCESSABLE,
+		"message":      "the request failed validation",
+		"field_errors": fieldErrors,
+	}}
+
+	err := app.writeJSON(w, UNPROCESSABLE, env, nil)
+	if err != nil {
+		app.serverErrorJSON(w, r, err)
+	}
+}
+
+// Return the API token used to authenticate the current request, if any.
+func (app *application) apiToken(r *http.Request) (models.Token, bool) {
+	token, ok := r.Context().Value(apiTokenContextKey).(models.Token)
+	return token, ok
+}
@@ -9,21 +9,47 @@ package main
 */
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"html/template"
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Galbeyte1/snippetbox/internal/models"
+	"github.com/alexedwards/scs/mysqlstore"
+	"github.com/alexedwards/scs/postgresstore"
+	"github.com/alexedwards/scs/sqlite3store"
+	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
 )
 
 type config struct {
-	addr      string
-	staticDir string
+	addr            string
+	staticDir       string
+	store           string
+	migrate         bool
+	shutdownTimeout time.Duration
+	session         struct {
+		idleTimeout time.Duration
+		lifetime    time.Duration
+	}
+	reaper struct {
+		interval  time.Duration
+		batchSize int
+	}
+	tls struct {
+		certFile     string
+		keyFile      string
+		redirectAddr string
+	}
 }
 
 /*
@@ -46,10 +72,16 @@ type config struct {
 // Define an application struct to hold the application-wide dependencies for the
 // web applicion.
 type application struct {
-	logger        *slog.Logger
-	snippets      *models.SnippetModel
-	templateCache map[string]*template.Template
-	formDecoder   *form.Decoder
+	logger         *slog.Logger
+	snippets       models.SnippetStore
+	users          models.UserStore
+	tokens         models.TokenStore
+	templateCache  map[string]*template.Template
+	formDecoder    *form.Decoder
+	sessionManager *scs.SessionManager
+	// wg tracks the background goroutines, so that we can wait for them to
+	// finish before exiting.
+	wg sync.WaitGroup
 }
 
 func main() {
@@ -62,7 +94,27 @@ func main() {
 	// A quirk of our MySQL driver is that we need to use the parseTime=true
 	// parameter in our DSN to force it to convert TIME and DATE fields to time.Time.
 	// Otherwise it returns these as []byte objects.
-	dsn := flag.String("dsn", "web:YES@/snippetbox?parseTime=true", "MySQL data source name")
+	// A DSN like sqlite://./snippetbox.db uses a SQLite database file instead,
+	// and a postgres:// URL uses PostgreSQL.
+	dsn := flag.String("dsn", "web:YES@/snippetbox?parseTime=true", "Data source name (MySQL DSN, sqlite://path or postgres://url)")
+	// The -store flag selects where snippets are kept. Users, sessions and
+	// API tokens always live in the database.
+	flag.StringVar(&cfg.store, "store", "db", "Snippet store (db|memory)")
+	flag.BoolVar(&cfg.migrate, "migrate", false, "Apply pending database migrations at startup")
+	flag.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", 30*time.Second, "Time to wait for in-flight requests on shutdown")
+	// When a certificate and key are given the server uses HTTPS. For local
+	// development, generate a self-signed pair with "go run ./cmd/devcert".
+	flag.StringVar(&cfg.tls.certFile, "tls-cert", "", "Path to TLS certificate file (enables HTTPS)")
+	flag.StringVar(&cfg.tls.keyFile, "tls-key", "", "Path to TLS private key file")
+	flag.StringVar(&cfg.tls.redirectAddr, "http-redirect-addr", "", "Plain HTTP address which redirects to HTTPS (e.g. :80)")
+	// A session expires if it hasn't been used for the idle timeout, and
+	// regardless of activity once the absolute lifetime has passed.
+	flag.DurationVar(&cfg.session.idleTimeout, "session-idle-timeout", 2*time.Hour, "Session idle timeout")
+	flag.DurationVar(&cfg.session.lifetime, "session-lifetime", 12*time.Hour, "Session absolute lifetime")
+	// Expired snippets are hidden straight away, but only deleted from the
+	// store by the background reaper. An interval of 0 disables the reaper.
+	flag.DurationVar(&cfg.reaper.interval, "reap-interval", time.Hour, "Interval between deleting expired snippets (0 to disable)")
+	flag.IntVar(&cfg.reaper.batchSize, "reap-batch-size", 500, "Maximum number of expired snippets to delete per query")
 
 	flag.Parse()
 
@@ -73,10 +125,31 @@ func main() {
 		AddSource: true,
 	}))
 
+	// Check the flag values before connecting to anything. A batch size
+	// below 1 would leave the reaper deleting nothing, over and over.
+	if cfg.reaper.batchSize < 1 {
+		logger.Error("-reap-batch-size must be at least 1", "reap-batch-size", cfg.reaper.batchSize)
+		os.Exit(1)
+	}
+
+	// HTTPS needs both a certificate and a key. Rather than silently serving
+	// plain HTTP when only one of them is given, refuse to start.
+	if (cfg.tls.certFile == "") != (cfg.tls.keyFile == "") {
+		logger.Error("-tls-cert and -tls-key must be used together")
+		os.Exit(1)
+	}
+
+	if cfg.tls.redirectAddr != "" && cfg.tls.certFile == "" {
+		logger.Error("-http-redirect-addr needs -tls-cert and -tls-key")
+		os.Exit(1)
+	}
+
 	// To keep the main() tidy I've put the code for creating a connection
 	// pool into the seperate openDB() function below. We pass openDB()
-	// the DSN from the command-line flag.
-	db, err := openDB(*dsn)
+	// the driver and DSN worked out from the command-line flag.
+	driver, source := parseDSN(*dsn)
+
+	db, err := openDB(driver, source)
 	if err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
@@ -86,6 +159,26 @@ func main() {
 	// is closed before the main() function exits.
 	defer db.Close()
 
+	// If the binary was run as "web migrate up|down|status", manage the
+	// database schema and then exit instead of starting the server.
+	if flag.Arg(0) == "migrate" {
+		err = runMigrate(os.Stdout, db, driver, flag.Args()[1:])
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Otherwise apply any pending migrations at startup, if asked to.
+	if cfg.migrate {
+		err = runMigrate(os.Stdout, db, driver, []string{"up"})
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
 	// Initialize a new template cache
 	templateCache, err := newTemplateCache()
 	if err != nil {
@@ -93,34 +186,109 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Initialize the snippet store selected by the -store flag.
+	var snippets models.SnippetStore
+
+	switch cfg.store {
+	// The database store was called "mysql" before the other databases were
+	// supported, so that name is still accepted.
+	case "db", "mysql":
+		switch driver {
+		case "sqlite":
+			snippets = &models.SQLiteSnippetModel{DB: db}
+		case "pgx":
+			snippets = &models.PostgresSnippetModel{DB: db}
+		default:
+			snippets = &models.SnippetModel{DB: db}
+		}
+	case "memory":
+		snippets = models.NewMemorySnippetModel()
+	default:
+		logger.Error("unknown snippet store", "store", cfg.store)
+		os.Exit(1)
+	}
+
 	formDecoder := form.NewDecoder()
 
+	// Use the scs.New() function to initialize a new session manager. Then we
+	// configure it to use our database as the session store, so that session
+	// data lives in the "sessions" table and survives restarts. The browser
+	// only ever holds the session token in a cookie.
+	sessionManager := scs.New()
+	switch driver {
+	case "sqlite":
+		sessionManager.Store = sqlite3store.New(db)
+	case "pgx":
+		sessionManager.Store = postgresstore.New(db)
+	default:
+		sessionManager.Store = mysqlstore.New(db)
+	}
+	sessionManager.IdleTimeout = cfg.session.idleTimeout
+	sessionManager.Lifetime = cfg.session.lifetime
+	sessionManager.Cookie.Name = "session"
+	sessionManager.Cookie.HttpOnly = true
+	sessionManager.Cookie.SameSite = http.SameSiteLaxMode
+	// When serving over HTTPS, only send the session cookie over HTTPS too.
+	sessionManager.Cookie.Secure = cfg.tls.certFile != "" && cfg.tls.keyFile != ""
+
 	// Initialize a new instance of our application struct, containing the
 	// dependencies
 	// Structured Logger and initialized SnippetModel containing conn pool
 	app := &application{
-		logger:        logger,
-		snippets:      &models.SnippetModel{DB: db},
-		templateCache: templateCache,
-		formDecoder:   formDecoder,
-	}
-
-	// Print a log message to say that the server is starting.
-	logger.Info("starting server on", "addr", cfg.addr)
-
-	// Use the http.ListenAndServe() function to start a new web server. We pass in
-	// two parameters: the TCP network address to listen on (in this case ":4000")
-	// and the servemux that we get from app.routes().
-	// If http.ListenAndServe() returns an error
-	// we use the logger.Error() function to log the error message and exit. Note
-	// that any error returned by http.ListenAndServe() is always non-nil.
-	err = http.ListenAndServe(cfg.addr, app.routes())
-	logger.Error(err.Error())
-	os.Exit(1)
+		logger:         logger,
+		snippets:       snippets,
+		users:          &models.UserModel{DB: db, Driver: driver},
+		tokens:         &models.TokenModel{DB: db, Driver: driver},
+		templateCache:  templateCache,
+		formDecoder:    formDecoder,
+		sessionManager: sessionManager,
+	}
+
+	// Start the background worker which deletes expired snippets. Cancelling
+	// the context stops it.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+
+	if cfg.reaper.interval > 0 {
+		app.wg.Add(1)
+		go func() {
+			defer app.wg.Done()
+			app.reapExpiredSnippets(workerCtx, cfg.reaper.interval, cfg.reaper.batchSize)
+		}()
+	}
+
+	// Run the HTTP server. This blocks until the server is shut down by a
+	// SIGINT or SIGTERM signal, or fails to start.
+	err = app.serve(cfg)
+
+	// Stop the background workers and wait for them to finish. The deferred
+	// db.Close() then closes the connection pool when main() returns.
+	stopWorkers()
+	app.wg.Wait()
+
+	if err != nil {
+		logger.Error(err.Error())
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+func parseDSN(dsn string) (string, string) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return "pgx", dsn
+	}
+
+	if path, ok := strings.CutPrefix(dsn, "sqlite://"); ok {
+		// Enforce foreign keys (which SQLite doesn't do by default), wait for
+		// locks instead of failing straight away, and use write-ahead logging
+		// so that readers don't block the writer.
+		return "sqlite", "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	}
+
+	return "mysql", dsn
 }
 
-func openDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", dsn)
+func openDB(driver string, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/Galbeyte1/snippetbox/internal/models"
+	"github.com/justinas/nosurf"
 )
 
 func commonHeaders(next http.Handler) http.Handler {
@@ -16,6 +22,12 @@ func commonHeaders(next http.Handler) http.Handler {
 
 		w.Header().Set("Server", "Go")
 
+		// Tell browsers to only use HTTPS for this site from now on, but only
+		// when the request actually arrived over HTTPS.
+		if r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -26,7 +38,7 @@ func (app *application) logRequest(next http.Handler) http.Handler {
 			ip     = r.RemoteAddr
 			proto  = r.Proto
 			method = r.Method
-			uri    = r.URL.RequestURI()
+			uri    = logURI(r)
 		)
 
 		app.logger.Info("received request", "ip", ip, "proto", proto, "method", method, "uri", uri)
@@ -48,3 +60,113 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+func (app *application) requireAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// If the user is not authenticated, redirect them to the login page and
+		// return from the middleware chain so that no subsequent handlers in
+		// the chain are executed.
+		if !app.isAuthenticated(r) {
+			http.Redirect(w, r, "/user/login", SEE_OTHER)
+			return
+		}
+
+		// Otherwise set the "Cache-Control: no-store" header so that pages
+		// that require authentication are not stored in the user's browser
+		// cache (or other intermediary cache).
+		w.Header().Add("Cache-Control", "no-store")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Retrieve the authenticatedUserID value from the session using the
+		// GetInt() method. This will return the zero value for an int (0) if no
+		// "authenticatedUserID" value is in the session -- in which case we
+		// call the next handler in the chain as normal and return.
+		id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+		if id == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Otherwise, we check to see if a user with that ID exists in our
+		// database.
+		exists, err := app.users.Exists(id)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		// If a matching user is found, we know that the request is
+		// coming from an authenticated user who exists in our database. We
+		// create a new copy of the request (with an isAuthenticatedContextKey
+		// value of true in the request context) and assign it to r.
+		if exists {
+			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+			r = r.WithContext(ctx)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Create a noSurf middleware function which uses a customized CSRF cookie with
+// the Path and HttpOnly attributes set, and the Secure attribute whenever the
+// session cookie has it (that is, when we are serving over HTTPS). nosurf
+// checks the token on every request with a non-safe method (POST, PUT, DELETE
+// etc.), so it protects all of our state-changing forms.
+func (app *application) noSurf(next http.Handler) http.Handler {
+	csrfHandler := nosurf.New(next)
+	csrfHandler.SetBaseCookie(http.Cookie{
+		HttpOnly: true,
+		Path:     "/",
+		Secure:   app.sessionManager.Cookie.Secure,
+	})
+
+	// Send a 400 Bad Request response through our clientError helper when
+	// the CSRF token is missing or doesn't match.
+	csrfHandler.SetFailureHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.clientError(w, BAD_REQUEST)
+	}))
+
+	return csrfHandler
+}
+
+// The authenticateToken middleware checks for an "Authorization: Bearer"
+// header on API requests. Requests without one carry on anonymously, but a
+// header containing an unknown token is rejected with a 401 response.
+func (app *application) authenticateToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Indicate to any caches that the response may vary based on the
+		// value of the Authorization header.
+		w.Header().Add("Vary", "Authorization")
+
+		authorizationHeader := r.Header.Get("Authorization")
+		if authorizationHeader == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, token, ok := strings.Cut(authorizationHeader, " ")
+		if !ok || scheme != "Bearer" || token == "" {
+			app.invalidTokenJSON(w, r)
+			return
+		}
+
+		apiToken, err := app.tokens.Authenticate(token)
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidCredentials) {
+				app.invalidTokenJSON(w, r)
+			} else {
+				app.serverErrorJSON(w, r, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), apiTokenContextKey, apiToken)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
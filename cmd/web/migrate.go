@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/Galbeyte1/snippetbox/internal/migrations"
+)
+
+// runMigrate implements the "migrate up|down|status" subcommand, writing
+// human-readable output to w.
+func runMigrate(w io.Writer, db *sql.DB, driver string, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: web [flags] migrate up|down|status")
+	}
+
+	migrator, err := migrations.New(db, driver)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Fprintf(w, "applied %s\n", m)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(w, "no pending migrations")
+		}
+
+	case "down":
+		rolledBack, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if rolledBack == nil {
+			fmt.Fprintln(w, "no migrations to roll back")
+		} else {
+			fmt.Fprintf(w, "rolled back %s\n", rolledBack)
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "MIGRATION\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			if s.Applied {
+				fmt.Fprintf(tw, "%s\tapplied\t%s\n", s.Migration, s.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Fprintf(tw, "%s\tpending\t\n", s.Migration)
+			}
+		}
+		tw.Flush()
+
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+
+	return nil
+}
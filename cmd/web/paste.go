@@ -0,0 +1,168 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Galbeyte1/snippetbox/internal/models"
+)
+
+// maxPasteBytes limits the size of the request body accepted by the paste
+// endpoint. It leaves room for the multipart headers around the largest
+// content allowed by snippetCreateForm (1000 characters of up to 4 bytes).
+const maxPasteBytes = 16 * 1024
+
+// The paste handler creates a snippet from a plain request body, so that it
+// can be used straight from a terminal:
+//
+//	curl --data-binary @notes.txt localhost:4000/paste
+//	curl -F file=@main.go "localhost:4000/paste?expires=7"
+//
+// The body is either the content itself, or a multipart/form-data upload
+// whose first file is the content. The title, expiry, language and tags come
+// from the query string parameters or the X-Title, X-Expires, X-Language and
+// X-Tags headers. It responds with the URL of the new snippet as plain text,
+// and the URL for deleting it in the X-Delete-URL header.
+func (app *application) paste(w http.ResponseWriter, r *http.Request) {
+	userID := 0
+
+	if token, ok := app.apiToken(r); ok {
+		if !token.Allows(models.ScopeWrite) {
+			http.Error(w, "this token does not have the write scope", FORBIDDEN)
+			return
+		}
+
+		userID = token.UserID
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasteBytes)
+
+	content, filename, err := readPaste(r)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		if errors.As(err, &maxBytesError) {
+			http.Error(w, fmt.Sprintf("the body must not be larger than %d bytes", maxPasteBytes), http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, err.Error(), BAD_REQUEST)
+		}
+		return
+	}
+
+	form := snippetCreateForm{
+		Title:    pasteParam(r, "title", "X-Title"),
+		Content:  content,
+		Language: pasteParam(r, "language", "X-Language"),
+		Tags:     pasteParam(r, "tags", "X-Tags"),
+		Expires:  365,
+	}
+
+	// Use the uploaded file's name as the title if none was given.
+	if form.Title == "" {
+		form.Title = cmp.Or(filename, "Untitled")
+	}
+
+	if expires := pasteParam(r, "expires", "X-Expires"); expires != "" {
+		form.Expires, err = strconv.Atoi(expires)
+		if err != nil {
+			form.Expires = 0
+		}
+	}
+
+	form.CheckField(utf8.ValidString(form.Content), "content", "This field must be UTF-8 text")
+	form.validate()
+
+	if !form.Valid() {
+		var b strings.Builder
+
+		fields := make([]string, 0, len(form.FieldErrors))
+		for field := range form.FieldErrors {
+			fields = append(fields, field)
+		}
+		slices.Sort(fields)
+
+		for _, field := range fields {
+			fmt.Fprintf(&b, "%s: %s\n", field, form.FieldErrors[field])
+		}
+
+		http.Error(w, b.String(), UNPROCESSABLE)
+		return
+	}
+
+	id, key, err := app.snippets.Insert(form.Title, form.Content, form.Language, form.Expires, userID, 0, form.TagList)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	snippetURL := fmt.Sprintf("%s://%s/snippet/view/%d", scheme, r.Host, id)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Location", snippetURL)
+	w.Header().Set("X-Delete-URL", fmt.Sprintf("%s://%s/snippet/delete/%d?key=%s", scheme, r.Host, id, key))
+	w.WriteHeader(CREATED)
+
+	fmt.Fprintln(w, snippetURL)
+}
+
+// readPaste returns the content of a paste request, along with the name of
+// the uploaded file for multipart/form-data requests.
+func readPaste(r *http.Request) (string, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if mediaType != "multipart/form-data" {
+		b, err := io.ReadAll(r.Body)
+		return string(b), "", err
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return "", "", err
+	}
+
+	// Use the first part which is a file, ignoring any other form fields.
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return "", "", errors.New("the multipart body must contain a file")
+		}
+		if err != nil {
+			return "", "", err
+		}
+
+		if part.FileName() == "" {
+			continue
+		}
+
+		b, err := io.ReadAll(part)
+		if err != nil {
+			return "", "", err
+		}
+
+		return string(b), path.Base(part.FileName()), nil
+	}
+}
+
+// pasteParam returns the value of a query string parameter, or of a header if
+// the parameter isn't set.
+func pasteParam(r *http.Request, param string, header string) string {
+	if v := r.URL.Query().Get(param); v != "" {
+		return v
+	}
+
+	return r.Header.Get(header)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Galbeyte1/snippetbox/internal/models"
+)
+
+func TestPaste(t *testing.T) {
+	app := newTestApplication(t)
+	userID := newTestUser(t, app, "alice@example.com")
+
+	token, err := app.tokens.Insert(userID, "cli", models.ScopeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	var upload bytes.Buffer
+	mw := multipart.NewWriter(&upload)
+	fw, err := mw.CreateFormFile("file", "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte("package main\n"))
+	mw.Close()
+
+	tests := []struct {
+		name        string
+		urlPath     string
+		header      http.Header
+		body        []byte
+		wantCode    int
+		wantTitle   string
+		wantContent string
+		wantUserID  int
+	}{
+		{
+			name:        "Plain text",
+			urlPath:     "/paste?title=Notes&expires=7",
+			body:        []byte("Climb Mount Fuji\n"),
+			wantCode:    http.StatusCreated,
+			wantTitle:   "Notes",
+			wantContent: "Climb Mount Fuji\n",
+		},
+		{
+			name:        "Headers at /",
+			urlPath:     "/",
+			header:      http.Header{"X-Title": {"Haiku"}, "X-Tags": {"poems"}},
+			body:        []byte("O snail"),
+			wantCode:    http.StatusCreated,
+			wantTitle:   "Haiku",
+			wantContent: "O snail",
+		},
+		{
+			name:        "File upload",
+			urlPath:     "/paste",
+			header:      http.Header{"Content-Type": {mw.FormDataContentType()}},
+			body:        upload.Bytes(),
+			wantCode:    http.StatusCreated,
+			wantTitle:   "main.go",
+			wantContent: "package main\n",
+		},
+		{
+			name:        "Write token",
+			urlPath:     "/paste",
+			header:      http.Header{"Authorization": {"Bearer " + token}},
+			body:        []byte("Owned"),
+			wantCode:    http.StatusCreated,
+			wantTitle:   "Untitled",
+			wantContent: "Owned",
+			wantUserID:  userID,
+		},
+		{
+			name:     "Unknown token",
+			urlPath:  "/paste",
+			header:   http.Header{"Authorization": {"Bearer wrong"}},
+			body:     []byte("content"),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Empty body",
+			urlPath:  "/paste",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Invalid expiry",
+			urlPath:  "/paste?expires=2",
+			body:     []byte("content"),
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Too large",
+			urlPath:  "/paste",
+			body:     bytes.Repeat([]byte("a"), maxPasteBytes+1),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.do(t, http.MethodPost, tt.urlPath, tt.header, tt.body)
+
+			if code != tt.wantCode {
+				t.Fatalf("got status %d with body %q; want %d", code, body, tt.wantCode)
+			}
+
+			if code != http.StatusCreated {
+				return
+			}
+
+			if !strings.HasPrefix(header.Get("X-Delete-URL"), ts.URL+"/snippet/delete/") {
+				t.Errorf("got X-Delete-URL %q; want a delete URL", header.Get("X-Delete-URL"))
+			}
+
+			location := strings.TrimSpace(body)
+			if header.Get("Location") != location {
+				t.Errorf("got Location %q and body %q; want both to be the snippet URL", header.Get("Location"), location)
+			}
+
+			id, err := strconv.Atoi(strings.TrimPrefix(location, ts.URL+"/snippet/view/"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			snippet, err := app.snippets.Get(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if snippet.Title != tt.wantTitle || snippet.Content != tt.wantContent || snippet.UserID != tt.wantUserID {
+				t.Errorf("got snippet %+v; want title %q, content %q and user %d", snippet, tt.wantTitle, tt.wantContent, tt.wantUserID)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// reapExpiredSnippets runs until ctx is cancelled, permanently deleting
+// expired snippets every interval. Snippets are deleted in batches of at most
+// batchSize, so that a large backlog doesn't hold locks on the snippets table
+// for a long time.
+func (app *application) reapExpiredSnippets(ctx context.Context, interval time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		total := 0
+
+		for ctx.Err() == nil {
+			n, err := app.snippets.DeleteExpired(batchSize)
+			if err != nil {
+				app.logger.Error("reaping expired snippets", "error", err.Error())
+				break
+			}
+
+			total += n
+
+			// An empty batch, or one that wasn't full, means there is nothing
+			// left to delete.
+			if n == 0 || n < batchSize {
+				break
+			}
+		}
+
+		if total > 0 {
+			app.logger.Info("deleted expired snippets", "count", total)
+		}
+	}
+}
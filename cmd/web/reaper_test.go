@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Galbeyte1/snippetbox/internal/models"
+)
+
+// reapRecorder wraps a SnippetStore, recording how many snippets each call to
+// DeleteExpired deleted.
+type reapRecorder struct {
+	models.SnippetStore
+	deleted chan int
+}
+
+func (r *reapRecorder) DeleteExpired(limit int) (int, error) {
+	n, err := r.SnippetStore.DeleteExpired(limit)
+	r.deleted <- n
+	return n, err
+}
+
+func TestReapExpiredSnippets(t *testing.T) {
+	app := newTestApplication(t)
+
+	liveID, _, err := app.snippets.Insert("Live", "content", "", 7, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 5 {
+		_, _, err := app.snippets.Insert("Expired", "content", "", 0, 0, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder := &reapRecorder{SnippetStore: app.snippets, deleted: make(chan int, 100)}
+	app.snippets = recorder
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		app.reapExpiredSnippets(ctx, time.Millisecond, 2)
+		close(done)
+	}()
+
+	// The first run deletes full batches until one isn't full.
+	var got []int
+	for len(got) < 3 {
+		select {
+		case n := <-recorder.deleted:
+			got = append(got, n)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got batches %v before timing out; want [2 2 1]", got)
+		}
+	}
+
+	cancel()
+	<-done
+
+	if !slices.Equal(got, []int{2, 2, 1}) {
+		t.Errorf("got batches %v; want [2 2 1]", got)
+	}
+
+	_, err = app.snippets.Get(liveID)
+	if err != nil {
+		t.Errorf("got error %v for the live snippet; want it kept", err)
+	}
+}
@@ -22,11 +22,66 @@ func (app *application) routes() http.Handler {
 	// all URL paths that start with "/static/". For matching paths, we strip the
 	// "/static" prefix before the request reaches the file server.
 	mux.Handle("GET /static/", http.StripPrefix("/static", fileServer))
+	mux.HandleFunc("GET /static/css/highlight.css", app.highlightCSS)
 
-	mux.HandleFunc("GET /{$}", app.home)
-	mux.HandleFunc("GET /snippet/view/{id}", app.snippetView)
-	mux.HandleFunc("GET /snippet/create", app.snippetCreate)
-	mux.HandleFunc("POST /snippet/create", app.snippetCreatePost)
+	// The raw, download and patch routes serve plain text for scripts, so they
+	// don't need the CSRF protection or authentication of the dynamic routes.
+	mux.HandleFunc("GET /snippet/raw/{id}", app.snippetRaw)
+	mux.HandleFunc("GET /snippet/download/{id}", app.snippetDownload)
+	mux.HandleFunc("GET /snippet/diff/{a}/{b}/patch", app.snippetPatch)
+
+	// Create a new middleware chain containing the middleware specific to our
+	// dynamic application routes: noSurf for CSRF protection and the
+	// authenticate middleware, which checks whether the session belongs to a
+	// user who still exists.
+	dynamic := alice.New(app.noSurf, app.authenticate)
+
+	mux.Handle("GET /{$}", dynamic.ThenFunc(app.home))
+	mux.Handle("GET /snippets", dynamic.ThenFunc(app.snippetList))
+	mux.Handle("GET /tag/{name}", dynamic.ThenFunc(app.tagView))
+	mux.Handle("GET /snippet/view/{id}", dynamic.ThenFunc(app.snippetView))
+	mux.Handle("GET /snippet/view/{id}/history", dynamic.ThenFunc(app.snippetHistory))
+	mux.Handle("GET /snippet/search", dynamic.ThenFunc(app.snippetSearch))
+	mux.Handle("GET /snippet/diff/{a}/{b}", dynamic.ThenFunc(app.snippetDiff))
+	mux.Handle("GET /snippet/create", dynamic.ThenFunc(app.snippetCreate))
+	mux.Handle("POST /snippet/create", dynamic.ThenFunc(app.snippetCreatePost))
+	mux.Handle("GET /snippet/fork/{id}", dynamic.ThenFunc(app.snippetFork))
+	// Deleting is available without logging in, for anyone holding the
+	// snippet's delete key.
+	mux.Handle("GET /snippet/delete/{id}", dynamic.ThenFunc(app.snippetDelete))
+	mux.Handle("POST /snippet/delete/{id}", dynamic.ThenFunc(app.snippetDeletePost))
+	mux.Handle("GET /user/signup", dynamic.ThenFunc(app.userSignup))
+	mux.Handle("POST /user/signup", dynamic.ThenFunc(app.userSignupPost))
+	mux.Handle("GET /user/login", dynamic.ThenFunc(app.userLogin))
+	mux.Handle("POST /user/login", dynamic.ThenFunc(app.userLoginPost))
+
+	// Routes which should only be available to authenticated users use the
+	// protected middleware chain, which includes requireAuthentication.
+	protected := dynamic.Append(app.requireAuthentication)
+
+	mux.Handle("GET /snippet/edit/{id}", protected.ThenFunc(app.snippetEdit))
+	mux.Handle("POST /snippet/edit/{id}", protected.ThenFunc(app.snippetEditPost))
+	mux.Handle("POST /snippet/restore/{id}", protected.ThenFunc(app.snippetRestorePost))
+	mux.Handle("POST /user/logout", protected.ThenFunc(app.userLogoutPost))
+	mux.Handle("GET /account/tokens", protected.ThenFunc(app.accountTokens))
+	mux.Handle("POST /account/tokens", protected.ThenFunc(app.accountTokensPost))
+	mux.Handle("POST /account/tokens/{id}/revoke", protected.ThenFunc(app.accountTokenRevokePost))
+
+	// The JSON API routes don't use the dynamic chain: API clients don't send
+	// CSRF tokens, and requests are never authenticated by session cookie.
+	// Instead they may authenticate with a personal API token.
+	api := alice.New(app.authenticateToken)
+
+	mux.Handle("GET /api/v1/snippets", api.ThenFunc(app.apiSnippetList))
+	mux.Handle("GET /api/v1/snippets/search", api.ThenFunc(app.apiSnippetSearch))
+	mux.Handle("GET /api/v1/snippets/{id}", api.ThenFunc(app.apiSnippetView))
+	mux.Handle("POST /api/v1/snippets", api.ThenFunc(app.apiSnippetCreate))
+	mux.Handle("DELETE /api/v1/snippets/{id}", api.ThenFunc(app.apiSnippetDelete))
+
+	// The paste endpoint is for creating snippets with tools like curl, so it
+	// also uses the api chain and can be called at / as well as /paste.
+	mux.Handle("POST /{$}", api.ThenFunc(app.paste))
+	mux.Handle("POST /paste", api.ThenFunc(app.paste))
 
 	// all incoming HTTP requests are served in their own goroutine.
 	// For busy servers, this means it’s very likely that the code in
@@ -37,10 +92,14 @@ func (app *application) routes() http.Handler {
 
 	// Create middleware chain containing our `standard` middleware
 	// which will be used for every request our application receives.
+	// LoadAndSave loads the session for the request from the store and
+	// commits any changes (and the session cookie) before the response is
+	// written, so handlers can put/get/pop session values.
 	standard := alice.New(
 		app.recoverPanic,
 		app.logRequest,
 		commonHeaders,
+		app.sessionManager.LoadAndSave,
 	)
 
 	return standard.Then(mux)
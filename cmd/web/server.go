@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serve runs the HTTP server until it receives a SIGINT or SIGTERM signal. It
+// then stops accepting new connections and waits up to cfg.shutdownTimeout
+// for in-flight requests to complete before returning. If a TLS certificate
+// and key are configured the server uses HTTPS, optionally with a second
+// plain HTTP listener which redirects to it.
+func (app *application) serve(cfg config) error {
+	useTLS := cfg.tls.certFile != "" && cfg.tls.keyFile != ""
+
+	// Initialize a new http.Server struct instead of using
+	// http.ListenAndServe(), so that we can configure timeouts and graceful
+	// shutdown. The ErrorLog field takes a *log.Logger, so we use
+	// slog.NewLogLogger() to send the server's own error messages (such as
+	// TLS handshake failures) through our structured logger at Error level.
+	srv := &http.Server{
+		Addr:     cfg.addr,
+		Handler:  app.routes(),
+		ErrorLog: slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		// Close keep-alive connections after one minute of inactivity.
+		IdleTimeout: time.Minute,
+		// Limit the time to read the request headers and body, and to write
+		// the response, so that slow clients can't hold connections open
+		// forever.
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		// Limit the size of the request headers to 512KB.
+		MaxHeaderBytes: 512 * 1024,
+	}
+
+	if useTLS {
+		// Only allow TLS 1.2 and above, and prefer the elliptic curves which
+		// have assembly implementations. Go picks secure cipher suites by
+		// default, so we don't list them here.
+		srv.TLSConfig = &tls.Config{
+			MinVersion:       tls.VersionTLS12,
+			CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		}
+	}
+
+	// If asked to, start a second server on a plain HTTP address which
+	// redirects every request to the HTTPS server.
+	var redirectSrv *http.Server
+
+	if useTLS && cfg.tls.redirectAddr != "" {
+		redirectSrv = &http.Server{
+			Addr:              cfg.tls.redirectAddr,
+			Handler:           redirectToHTTPS(cfg.addr),
+			ErrorLog:          srv.ErrorLog,
+			IdleTimeout:       time.Minute,
+			ReadHeaderTimeout: 5 * time.Second,
+			MaxHeaderBytes:    srv.MaxHeaderBytes,
+		}
+
+		go func() {
+			app.logger.Info("starting redirect server", "addr", redirectSrv.Addr)
+
+			err := redirectSrv.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				app.logger.Error(err.Error(), "addr", redirectSrv.Addr)
+			}
+		}()
+	}
+
+	shutdownError := make(chan error)
+
+	// Start a background goroutine which waits for a shutdown signal.
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+		s := <-quit
+
+		app.logger.Info("shutting down server", "signal", s.String())
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+		defer cancel()
+
+		if redirectSrv != nil {
+			redirectSrv.Shutdown(ctx)
+		}
+
+		// Shutdown() makes ListenAndServe() return http.ErrServerClosed
+		// straight away, then waits for in-flight requests to complete
+		// (or for the context deadline to pass).
+		shutdownError <- srv.Shutdown(ctx)
+	}()
+
+	app.logger.Info("starting server", "addr", srv.Addr, "tls", useTLS)
+
+	var err error
+	if useTLS {
+		err = srv.ListenAndServeTLS(cfg.tls.certFile, cfg.tls.keyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	// Wait for Shutdown() to finish draining the in-flight requests.
+	err = <-shutdownError
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("stopped server", "addr", srv.Addr)
+
+	return nil
+}
+
+// redirectToHTTPS returns a handler which permanently redirects requests to
+// the same host and path on the HTTPS server listening on httpsAddr. We use
+// 308 Permanent Redirect rather than 301, so that clients repeat POST
+// requests instead of changing them to GET.
+func redirectToHTTPS(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
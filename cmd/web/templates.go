@@ -3,36 +3,242 @@ package main
 import (
 	"html/template"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/Galbeyte1/snippetbox/internal/diff"
+	"github.com/Galbeyte1/snippetbox/internal/highlight"
 	"github.com/Galbeyte1/snippetbox/internal/models"
+	"github.com/justinas/nosurf"
 )
 
 type templateData struct {
-	CurrentYear int
-	Snippet     models.Snippet
-	Snippets    []models.Snippet
-	Form        any
+	CurrentYear     int
+	Snippet         models.Snippet
+	Snippets        []models.Snippet
+	Forks           []models.Snippet
+	Tokens          []models.Token
+	NewToken        string
+	Form            any
+	Flash           string
+	IsAuthenticated bool
+	IsOwner         bool
+	DeleteKey       string
+	CSRFToken       string
+	Pagination      pagination
+	Tag             string
+	ListURL         string
+	Revisions       []revision
+	Diff            *revisionDiff
+	Comparison      *snippetComparison
+}
+
+// Define a snippetComparison type to hold the comparison of two snippets
+// shown on the diff page. Split is true for a side-by-side diff rather than a
+// unified one.
+type snippetComparison struct {
+	A     models.Snippet
+	B     models.Snippet
+	Split bool
+	Hunks []diff.Hunk
+}
+
+// Define a revision type to hold a snippet revision for the history page,
+// along with the name of its author (empty if the revision is anonymous).
+type revision struct {
+	models.Revision
+	Author string
+}
+
+// Define a revisionDiff type to hold the comparison of two revisions shown on
+// the history page. Split is true for a side-by-side diff rather than a
+// unified one.
+type revisionDiff struct {
+	From  revision
+	To    revision
+	Split bool
+	Hunks []diff.Hunk
+}
+
+// Define a pagination type holding the links to the previous and next pages
+// of a list of results. An empty URL means there is no such page. Page is
+// the current page number, for results which are numbered (the search
+// results are, but the keyset-paginated snippet listing isn't).
+type pagination struct {
+	Page    int
+	PrevURL string
+	NextURL string
+}
+
+// newPagination builds the previous and next page links for page of the
+// results at path, keeping the other query string parameters in query.
+func newPagination(path string, query url.Values, page int, more bool) pagination {
+	link := func(page int) string {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		if page > 1 {
+			q.Set("page", strconv.Itoa(page))
+		}
+
+		return path + "?" + q.Encode()
+	}
+
+	p := pagination{Page: page}
+
+	if page > 1 {
+		p.PrevURL = link(page - 1)
+	}
+	if more {
+		p.NextURL = link(page + 1)
+	}
+
+	return p
 }
 
 func (app *application) newTemplateData(r *http.Request) templateData {
 	return templateData{
 		CurrentYear: time.Now().Year(),
+		// Retrieve and remove the one-shot flash message (if any) from the
+		// session, so that it is displayed exactly once.
+		Flash:           app.sessionManager.PopString(r.Context(), "flash"),
+		IsAuthenticated: app.isAuthenticated(r),
+		CSRFToken:       nosurf.Token(r),
 	}
 }
 
+// newCursorPagination builds the previous and next page links for a page of
+// the snippet listing at path, from the cursors in the page.
+func newCursorPagination(path string, query url.Values, page models.SnippetPage) pagination {
+	link := func(param, cursor string) string {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set(param, cursor)
+
+		return path + "?" + q.Encode()
+	}
+
+	var p pagination
+
+	if page.Prev != "" {
+		p.PrevURL = link("before", page.Prev)
+	}
+	if page.Next != "" {
+		p.NextURL = link("after", page.Next)
+	}
+
+	return p
+}
+
 // Create a humanData function which returns a nicely formatted string
 // representation of a time.Time object.
 func humanDate(t time.Time) string {
 	return t.Format("02 Jan 2006 at 15:04")
 }
 
+// excerptLength is the approximate number of characters of content shown
+// around the first match in a search result.
+const excerptLength = 200
+
+// searchRX returns a case-insensitive regular expression matching any of the
+// words in a search query, or nil if there are none. Quotes and a leading
+// minus sign (used for phrases and exclusions by the PostgreSQL search) are
+// ignored, as is the OR operator.
+func searchRX(query string) *regexp.Regexp {
+	var terms []string
+
+	for _, term := range strings.Fields(query) {
+		term = strings.TrimLeft(strings.Trim(term, `"`), "-")
+		if term == "" || term == "OR" {
+			continue
+		}
+		terms = append(terms, regexp.QuoteMeta(term))
+	}
+
+	if len(terms) == 0 {
+		return nil
+	}
+
+	// Try longer words first, so that a word which contains another one is
+	// marked in full.
+	slices.SortFunc(terms, func(a, b string) int {
+		return len(b) - len(a)
+	})
+
+	return regexp.MustCompile("(?i)" + strings.Join(terms, "|"))
+}
+
+// markMatches HTML-escapes text and wraps each occurrence of the words in the
+// search query in <mark> tags.
+func markMatches(text, query string) template.HTML {
+	rx := searchRX(query)
+	if rx == nil {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+
+	var b strings.Builder
+
+	last := 0
+	for _, loc := range rx.FindAllStringIndex(text, -1) {
+		b.WriteString(template.HTMLEscapeString(text[last:loc[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(template.HTMLEscapeString(text[loc[0]:loc[1]]))
+		b.WriteString("</mark>")
+		last = loc[1]
+	}
+	b.WriteString(template.HTMLEscapeString(text[last:]))
+
+	return template.HTML(b.String())
+}
+
+// excerpt returns about excerptLength characters of text around the first
+// occurrence of a word in the search query (or from the start, if none of
+// them appear), with the matches marked as by markMatches.
+func excerpt(text, query string) template.HTML {
+	runes := []rune(text)
+
+	start := 0
+	if rx := searchRX(query); rx != nil {
+		if loc := rx.FindStringIndex(text); loc != nil {
+			// Start a quarter of the excerpt before the match, so that it
+			// has some context.
+			start = max(0, utf8.RuneCountInString(text[:loc[0]])-excerptLength/4)
+		}
+	}
+	end := min(len(runes), start+excerptLength)
+	start = max(0, min(start, end-excerptLength))
+
+	s := string(runes[start:end])
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(runes) {
+		s += "…"
+	}
+
+	return markMatches(s, query)
+}
+
 // Initialize a template.FuncMap object and store it in a global variable. This is
 // essentially a string-keyed map which acts as a lookup between the names of our
 // custom template functions and the functions themselves.
 var functions = template.FuncMap{
-	"humanDate": humanDate,
+	"humanDate":   humanDate,
+	"markMatches": markMatches,
+	"excerpt":     excerpt,
+	"highlight":   highlight.HTML,
+	"language":    highlight.Resolve,
+	"languages":   func() []highlight.Language { return highlight.Languages },
+	"sideBySide":  diff.SideBySide,
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
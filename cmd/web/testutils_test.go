@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Galbeyte1/snippetbox/internal/models"
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-playground/form/v4"
+)
+
+// The templates and static files are read from paths relative to the
+// project root, so run the tests from there.
+func TestMain(m *testing.M) {
+	err := os.Chdir("../..")
+	if err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// newTestApplication returns an application which keeps snippets, users, API
+// tokens and sessions in memory, so that the handlers can be tested without a
+// database.
+func newTestApplication(t *testing.T) *application {
+	templateCache, err := newTemplateCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionManager := scs.New()
+	sessionManager.Lifetime = 12 * time.Hour
+	sessionManager.Cookie.Secure = true
+
+	return &application{
+		logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		snippets:       models.NewMemorySnippetModel(),
+		users:          models.NewMemoryUserModel(),
+		tokens:         models.NewMemoryTokenModel(),
+		templateCache:  templateCache,
+		formDecoder:    form.NewDecoder(),
+		sessionManager: sessionManager,
+	}
+}
+
+// newTestUser adds a user to the application with the password "pa$$word",
+// returning their ID.
+func newTestUser(t *testing.T, app *application, email string) int {
+	t.Helper()
+
+	err := app.users.Insert("Test User", email, "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := app.users.Authenticate(email, "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+// Define a custom testServer type which embeds a httptest.Server instance.
+type testServer struct {
+	*httptest.Server
+}
+
+// newTestServer starts a HTTPS test server for the handler. Its client keeps
+// cookies between requests, and doesn't follow redirects.
+func newTestServer(t *testing.T, h http.Handler) *testServer {
+	ts := httptest.NewTLSServer(h)
+	t.Cleanup(ts.Close)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts.Client().Jar = jar
+	ts.Client().CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &testServer{ts}
+}
+
+// do sends a request with the given headers to the test server, returning
+// the response status code, headers and body.
+func (ts *testServer) do(t *testing.T, method string, urlPath string, header http.Header, body []byte) (int, http.Header, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, ts.URL+urlPath, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	b, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(b)
+}
+
+func (ts *testServer) get(t *testing.T, urlPath string) (int, http.Header, string) {
+	t.Helper()
+	return ts.do(t, http.MethodGet, urlPath, nil, nil)
+}
+
+func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (int, http.Header, string) {
+	t.Helper()
+	header := http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+	return ts.do(t, http.MethodPost, urlPath, header, []byte(form.Encode()))
+}
+
+// postJSON sends a JSON body to the API, authenticated with the given API
+// token unless it is empty.
+func (ts *testServer) postJSON(t *testing.T, urlPath string, token string, body string) (int, http.Header, string) {
+	t.Helper()
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	return ts.do(t, http.MethodPost, urlPath, header, []byte(body))
+}
+
+// login logs the test server's client in through the login form.
+func (ts *testServer) login(t *testing.T, email string) {
+	t.Helper()
+
+	_, _, body := ts.get(t, "/user/login")
+
+	form := url.Values{}
+	form.Add("email", email)
+	form.Add("password", "pa$$word")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("got status %d logging in; want %d", code, http.StatusSeeOther)
+	}
+}
+
+var csrfTokenRX = regexp.MustCompile(`<input type="hidden" name="csrf_token" value="(.+)" />`)
+
+// extractCSRFToken returns the CSRF token from a form in a HTML page.
+func extractCSRFToken(t *testing.T, body string) string {
+	t.Helper()
+
+	matches := csrfTokenRX.FindStringSubmatch(body)
+	if len(matches) < 2 {
+		t.Fatal("no csrf token found in body")
+	}
+
+	return html.UnescapeString(matches[1])
+}
@@ -0,0 +1,362 @@
+// Package diff compares two texts line by line, and groups the differences
+// into hunks for showing as a unified or side-by-side diff.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Define an Op type for the operations in a diff.
+type Op int
+
+const (
+	// Equal lines are in both texts.
+	Equal Op = iota
+	// Delete lines are only in the old text.
+	Delete
+	// Insert lines are only in the new text.
+	Insert
+)
+
+// String returns the name of the operation, which is also used as a CSS
+// class in the templates.
+func (o Op) String() string {
+	switch o {
+	case Delete:
+		return "delete"
+	case Insert:
+		return "insert"
+	default:
+		return "equal"
+	}
+}
+
+// Prefix returns the character marking a line with this operation in a
+// unified diff.
+func (o Op) Prefix() string {
+	switch o {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// Define a Line type to hold one line of a diff. Old and New are the line
+// numbers (starting at 1) in the old and new texts, or 0 if the line isn't in
+// that text. For a changed line which replaces (or is replaced by) a similar
+// one, Segments marks the parts which differ; otherwise it is nil.
+type Line struct {
+	Op       Op
+	Text     string
+	Old      int
+	New      int
+	Segments []Segment
+	// raw is the line as it appears in the text, including its line ending
+	// if it has one.
+	raw string
+}
+
+// Lines compares the old text a with the new text b, returning every line of
+// both in order. Within each run of changes, the deleted lines come before
+// the inserted ones. Lines are compared along with their line endings, so a
+// line which only gained or lost a "\r" or a final newline is changed too.
+func Lines(a, b string) []Line {
+	as, bs := splitLines(a), splitLines(b)
+
+	lines := make([]Line, 0, max(len(as), len(bs)))
+	i, j := 0, 0
+
+	for _, op := range script(as, bs) {
+		switch op {
+		case Equal:
+			lines = append(lines, Line{Op: Equal, Text: trimEOL(as[i]), Old: i + 1, New: j + 1, raw: as[i]})
+			i++
+			j++
+		case Delete:
+			lines = append(lines, Line{Op: Delete, Text: trimEOL(as[i]), Old: i + 1, raw: as[i]})
+			i++
+		case Insert:
+			lines = append(lines, Line{Op: Insert, Text: trimEOL(bs[j]), New: j + 1, raw: bs[j]})
+			j++
+		}
+	}
+
+	// Mark the differences within each deleted line and the inserted line
+	// which takes its place.
+	for _, row := range SideBySide(lines) {
+		if row.Old != nil && row.New != nil && row.Old.Op == Delete {
+			row.Old.Segments, row.New.Segments = Inline(row.Old.Text, row.New.Text)
+		}
+	}
+
+	return lines
+}
+
+// splitLines splits a text into lines, keeping their line endings. A final
+// line ending doesn't start another line.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// trimEOL removes the line ending from a line.
+func trimEOL(line string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+}
+
+// maxCells limits the size of the table used to find the longest common
+// subsequence. Snippets are far smaller than this, but if it is ever reached
+// the differing middle of the texts is shown as deleted and reinserted.
+const maxCells = 1 << 22
+
+// script returns the operations which turn a into b, keeping the longest
+// common subsequence of the two as Equal.
+func script[T comparable](a, b []T) []Op {
+	// Lines at the start and end of both texts are left out of the table.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]Op, 0, len(a)+len(b))
+
+	for range prefix {
+		ops = append(ops, Equal)
+	}
+
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(a), len(b)
+
+	if n*m > maxCells {
+		for range n {
+			ops = append(ops, Delete)
+		}
+		for range m {
+			ops = append(ops, Insert)
+		}
+	} else {
+		// lcs[i*(m+1)+j] is the length of the longest common subsequence
+		// of a[i:] and b[j:].
+		lcs := make([]int32, (n+1)*(m+1))
+
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+				} else {
+					lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && a[i] == b[j]:
+				ops = append(ops, Equal)
+				i++
+				j++
+			case j == m || (i < n && lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]):
+				ops = append(ops, Delete)
+				i++
+			default:
+				ops = append(ops, Insert)
+				j++
+			}
+		}
+	}
+
+	for range suffix {
+		ops = append(ops, Equal)
+	}
+
+	return ops
+}
+
+// Define a Hunk type to hold a group of nearby changes, along with the
+// unchanged lines around them. The start and count fields are the ones shown
+// in the @@ header of a unified diff.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []Line
+}
+
+// Header returns the hunk's header line in a unified diff, such as
+// "@@ -1,4 +1,5 @@".
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+// hunkRange formats the start and count of a hunk. A count of 1 is left out,
+// as in the output of diff -u.
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// Hunks groups the changed lines of a diff into hunks, with up to context
+// unchanged lines before and after each change. Changes which are close
+// enough for their context to overlap share a hunk. It returns nil if
+// nothing changed.
+func Hunks(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+
+	// start and end are the indexes of the lines in the current hunk, or
+	// -1 if there isn't one.
+	start, end := -1, -1
+
+	flush := func() {
+		if start < 0 {
+			return
+		}
+
+		h := Hunk{Lines: lines[start:end]}
+
+		// Find the first line of each text in the hunk, by counting the
+		// lines before it.
+		for _, l := range lines[:start] {
+			if l.Op != Insert {
+				h.OldStart++
+			}
+			if l.Op != Delete {
+				h.NewStart++
+			}
+		}
+
+		for _, l := range h.Lines {
+			if l.Op != Insert {
+				h.OldLines++
+			}
+			if l.Op != Delete {
+				h.NewLines++
+			}
+		}
+
+		// An empty range starts at the line before it, so only add 1 to
+		// non-empty ones.
+		if h.OldLines > 0 {
+			h.OldStart++
+		}
+		if h.NewLines > 0 {
+			h.NewStart++
+		}
+
+		hunks = append(hunks, h)
+		start = -1
+	}
+
+	for i, l := range lines {
+		if l.Op != Equal {
+			// Start a new hunk, unless this change is close enough to the
+			// end of the current one to join it.
+			if start >= 0 && i-context > end {
+				flush()
+			}
+			if start < 0 {
+				start = max(0, i-context)
+			}
+			end = min(len(lines), i+context+1)
+		}
+	}
+
+	flush()
+
+	return hunks
+}
+
+// Define a Row type to hold one row of a side-by-side diff. Old and New are
+// nil when the row is blank on that side.
+type Row struct {
+	Old *Line
+	New *Line
+}
+
+// SideBySide arranges the lines of a diff (or of a hunk) into rows, pairing
+// each run of deleted lines with the inserted lines which replace them.
+func SideBySide(lines []Line) []Row {
+	var rows []Row
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			rows = append(rows, Row{Old: &lines[i], New: &lines[i]})
+			i++
+			continue
+		}
+
+		var deleted, inserted []int
+
+		for ; i < len(lines) && lines[i].Op == Delete; i++ {
+			deleted = append(deleted, i)
+		}
+		for ; i < len(lines) && lines[i].Op == Insert; i++ {
+			inserted = append(inserted, i)
+		}
+
+		for k := range max(len(deleted), len(inserted)) {
+			var row Row
+
+			if k < len(deleted) {
+				row.Old = &lines[deleted[k]]
+			}
+			if k < len(inserted) {
+				row.New = &lines[inserted[k]]
+			}
+
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+// Unified returns the differences between the old text a and the new text b
+// in the unified format read by patch, with up to context unchanged lines
+// around each change. oldName and newName are used in the --- and +++ header
+// lines. It returns an empty string if the texts are the same.
+func Unified(oldName, newName, a, b string, context int) string {
+	hunks := Hunks(Lines(a, b), context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+
+	for _, h := range hunks {
+		sb.WriteString(h.Header())
+		sb.WriteByte('\n')
+
+		for _, l := range h.Lines {
+			sb.WriteString(l.Op.Prefix())
+			sb.WriteString(l.raw)
+
+			if !strings.HasSuffix(l.raw, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,287 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{"Both empty", "", "", ""},
+		{"Identical", "one\ntwo\n", "one\ntwo\n", ""},
+		{
+			"Empty old text",
+			"",
+			"one\ntwo\n",
+			"--- a\n+++ b\n@@ -0,0 +1,2 @@\n+one\n+two\n",
+		},
+		{
+			"Empty new text",
+			"one\n",
+			"",
+			"--- a\n+++ b\n@@ -1 +0,0 @@\n-one\n",
+		},
+		{
+			"Changed line",
+			"one\ntwo\nthree\n",
+			"one\n2\nthree\n",
+			"--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			"Missing final newline added",
+			"one\ntwo",
+			"one\ntwo\n",
+			"--- a\n+++ b\n@@ -1,2 +1,2 @@\n one\n-two\n\\ No newline at end of file\n+two\n",
+		},
+		{
+			"Missing final newline in both",
+			"one\ntwo",
+			"1\ntwo",
+			"--- a\n+++ b\n@@ -1,2 +1,2 @@\n-one\n+1\n two\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a", "b", tt.a, tt.b, 3); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		changed []int
+		lines   int
+		want    []string
+	}{
+		{"No changes", nil, 10, nil},
+		{"One change", []int{5}, 10, []string{"@@ -2,7 +2,7 @@"}},
+		{"Change at the start", []int{1}, 10, []string{"@@ -1,4 +1,4 @@"}},
+		{"Change at the end", []int{10}, 10, []string{"@@ -7,4 +7,4 @@"}},
+		// The 3 lines of context after the first change and before the
+		// second overlap, so the changes share a hunk.
+		{"Overlapping context", []int{2, 8}, 10, []string{"@@ -1,10 +1,10 @@"}},
+		{"Adjacent context", []int{2, 9}, 12, []string{"@@ -1,12 +1,12 @@"}},
+		{"Separate hunks", []int{2, 10}, 12, []string{"@@ -1,5 +1,5 @@", "@@ -7,6 +7,6 @@"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := numberedLines(tt.lines, nil), numberedLines(tt.lines, tt.changed)
+
+			var got []string
+			for _, h := range Hunks(Lines(a, b), 3) {
+				got = append(got, h.Header())
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got hunks %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInline(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		wantOld []Segment
+		wantNew []Segment
+	}{
+		{
+			"Changed word",
+			"x := 1 + y",
+			"x := 2 + y",
+			[]Segment{{"x := ", false}, {"1", true}, {" + y", false}},
+			[]Segment{{"x := ", false}, {"2", true}, {" + y", false}},
+		},
+		{
+			"Added words",
+			"return err",
+			"return nil, err",
+			[]Segment{{"return err", false}},
+			[]Segment{{"return ", false}, {"nil, ", true}, {"err", false}},
+		},
+		{"Nothing in common", "one two", "three four", nil, nil},
+		{"Only punctuation in common", "a, b", "c, d", nil, nil},
+		{"Both empty", "", "", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOld, gotNew := Inline(tt.old, tt.new)
+
+			if !reflect.DeepEqual(gotOld, tt.wantOld) || !reflect.DeepEqual(gotNew, tt.wantNew) {
+				t.Errorf("got %+v and %+v; want %+v and %+v", gotOld, gotNew, tt.wantOld, tt.wantNew)
+			}
+		})
+	}
+}
+
+// TestUnifiedRoundTrip checks that applying the output of Unified to the old
+// text gives the new one, like patch would.
+func TestUnifiedRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+	}{
+		{"Both empty", "", ""},
+		{"From empty", "", "one\ntwo\n"},
+		{"To empty", "one\ntwo\n", ""},
+		{"Identical", "one\ntwo\n", "one\ntwo\n"},
+		{"Changed line", "one\ntwo\nthree\n", "one\n2\nthree\n"},
+		{"Newline added", "one\ntwo", "one\ntwo\n"},
+		{"Newline removed", "one\ntwo\n", "one\ntwo"},
+		{"No final newlines", "one\ntwo", "one\n2"},
+		{"Windows line endings", "one\r\ntwo\r\n", "one\ntwo\r\n"},
+		{"Separate hunks", numberedLines(20, nil), numberedLines(20, []int{2, 17})},
+		{"Many changes", numberedLines(30, []int{1, 5, 6, 12, 20, 30}), numberedLines(25, []int{3, 9, 10, 24})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch := Unified("a", "b", tt.a, tt.b, 3)
+
+			got, err := applyPatch(tt.a, patch)
+			if err != nil {
+				t.Fatalf("applying %q: %v", patch, err)
+			}
+
+			if got != tt.b {
+				t.Errorf("got %q from applying %q; want %q", got, patch, tt.b)
+			}
+		})
+	}
+}
+
+// numberedLines returns n lines numbered from 1, with the lines whose numbers
+// are in changed marked as changed.
+func numberedLines(n int, changed []int) string {
+	var sb strings.Builder
+
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, "line %d", i)
+		for _, c := range changed {
+			if c == i {
+				sb.WriteString(" changed")
+			}
+		}
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+// applyPatch applies a unified diff from Unified to the old text it was made
+// from, checking that its context and deleted lines match.
+func applyPatch(old string, patch string) (string, error) {
+	if patch == "" {
+		return old, nil
+	}
+
+	olds := splitLines(old)
+	patchLines := splitLines(patch)
+
+	if len(patchLines) < 2 || !strings.HasPrefix(patchLines[0], "--- ") || !strings.HasPrefix(patchLines[1], "+++ ") {
+		return "", fmt.Errorf("missing file headers")
+	}
+
+	var sb strings.Builder
+	pos := 0
+
+	for i := 2; i < len(patchLines); {
+		oldStart, oldCount := parseRange(patchLines[i])
+		i++
+
+		start, err := strconv.Atoi(oldStart)
+		if err != nil || !strings.HasPrefix(patchLines[i-1], "@@ -") {
+			return "", fmt.Errorf("bad hunk header %q", patchLines[i-1])
+		}
+
+		// An empty range starts at the line before it.
+		if oldCount != "0" {
+			start--
+		}
+
+		if start < pos || start > len(olds) {
+			return "", fmt.Errorf("hunk starts at line %d, after line %d", start+1, pos)
+		}
+
+		for _, l := range olds[pos:start] {
+			sb.WriteString(l)
+		}
+		pos = start
+
+		// Read the hunk's lines, applying the missing newline marker to
+		// the line before it.
+		type hunkLine struct {
+			op   byte
+			text string
+		}
+
+		var lines []hunkLine
+
+		for ; i < len(patchLines) && !strings.HasPrefix(patchLines[i], "@@ "); i++ {
+			l := patchLines[i]
+
+			if strings.HasPrefix(l, `\`) {
+				if len(lines) == 0 {
+					return "", fmt.Errorf("missing newline marker before any line")
+				}
+				lines[len(lines)-1].text = strings.TrimSuffix(lines[len(lines)-1].text, "\n")
+				continue
+			}
+
+			lines = append(lines, hunkLine{op: l[0], text: l[1:]})
+		}
+
+		for _, l := range lines {
+			switch l.op {
+			case ' ', '-':
+				if pos >= len(olds) || olds[pos] != l.text {
+					return "", fmt.Errorf("line %d doesn't match %q", pos+1, l.text)
+				}
+				if l.op == ' ' {
+					sb.WriteString(l.text)
+				}
+				pos++
+			case '+':
+				sb.WriteString(l.text)
+			default:
+				return "", fmt.Errorf("unknown line %q", l.text)
+			}
+		}
+	}
+
+	for _, l := range olds[pos:] {
+		sb.WriteString(l)
+	}
+
+	return sb.String(), nil
+}
+
+// parseRange returns the start and count of the old range in a hunk header,
+// filling in a count of 1 where it is left out.
+func parseRange(header string) (string, string) {
+	r, _, _ := strings.Cut(strings.TrimPrefix(header, "@@ -"), " ")
+
+	start, count, ok := strings.Cut(r, ",")
+	if !ok {
+		count = "1"
+	}
+
+	return start, count
+}
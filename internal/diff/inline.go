@@ -0,0 +1,119 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Define a Segment type to hold part of a changed line. Changed is true if
+// the text isn't in the line it is being compared with.
+type Segment struct {
+	Text    string
+	Changed bool
+}
+
+// Inline compares a deleted line with the inserted line which replaces it,
+// word by word, returning the segments of each with the differences marked.
+// If the lines have nothing in common but whitespace and punctuation, it
+// returns nil for both, since marking every word wouldn't help anyone.
+func Inline(old, new string) ([]Segment, []Segment) {
+	a, b := words(old), words(new)
+	ops := script(a, b)
+
+	common := false
+	i, j := 0, 0
+
+	for _, op := range ops {
+		switch op {
+		case Equal:
+			if isWord(a[i]) {
+				common = true
+			}
+			i++
+			j++
+		case Delete:
+			i++
+		case Insert:
+			j++
+		}
+	}
+
+	if !common {
+		return nil, nil
+	}
+
+	var oldSegments, newSegments []Segment
+	i, j = 0, 0
+
+	for _, op := range ops {
+		switch op {
+		case Equal:
+			oldSegments = appendSegment(oldSegments, a[i], false)
+			newSegments = appendSegment(newSegments, b[j], false)
+			i++
+			j++
+		case Delete:
+			oldSegments = appendSegment(oldSegments, a[i], true)
+			i++
+		case Insert:
+			newSegments = appendSegment(newSegments, b[j], true)
+			j++
+		}
+	}
+
+	return oldSegments, newSegments
+}
+
+// appendSegment adds text to the last segment if it has the same Changed
+// value, or starts a new segment otherwise.
+func appendSegment(segments []Segment, text string, changed bool) []Segment {
+	if n := len(segments); n > 0 && segments[n-1].Changed == changed {
+		segments[n-1].Text += text
+		return segments
+	}
+
+	return append(segments, Segment{Text: text, Changed: changed})
+}
+
+// words splits a line into runs of letters, digits and underscores, runs of
+// whitespace, and single other characters, so that concatenating them gives
+// the line back.
+func words(s string) []string {
+	var tokens []string
+
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+
+		var end int
+
+		switch {
+		case isWordRune(r):
+			end = strings.IndexFunc(s, func(r rune) bool { return !isWordRune(r) })
+		case unicode.IsSpace(r):
+			end = strings.IndexFunc(s, func(r rune) bool { return !unicode.IsSpace(r) })
+		default:
+			end = size
+		}
+
+		if end < 0 {
+			end = len(s)
+		}
+
+		tokens = append(tokens, s[:end])
+		s = s[end:]
+	}
+
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isWord reports whether a token from words() is a word, rather than
+// whitespace or punctuation.
+func isWord(token string) bool {
+	r, _ := utf8.DecodeRuneInString(token)
+	return isWordRune(r)
+}
@@ -0,0 +1,179 @@
+// Package highlight turns snippet content into syntax-highlighted HTML using
+// chroma. The HTML uses CSS classes rather than inline styles, so that it is
+// allowed by the application's Content-Security-Policy; the matching
+// stylesheet is returned by CSS().
+package highlight
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// PlainText is the name of the language used for content which isn't code.
+const PlainText = "plaintext"
+
+// Define a Language type to describe one of the languages a snippet can be
+// highlighted as. Name is stored with the snippet and is also the name of the
+// chroma lexer, Label is shown to users, and Extension is used for file names.
+type Language struct {
+	Name      string
+	Label     string
+	Extension string
+}
+
+// Languages lists the languages users can choose from, in the order they are
+// shown in the snippet form.
+var Languages = []Language{
+	{PlainText, "Plain text", ".txt"},
+	{"bash", "Bash", ".sh"},
+	{"c", "C", ".c"},
+	{"cpp", "C++", ".cpp"},
+	{"css", "CSS", ".css"},
+	{"diff", "Diff", ".diff"},
+	{"docker", "Dockerfile", ".dockerfile"},
+	{"go", "Go", ".go"},
+	{"html", "HTML", ".html"},
+	{"java", "Java", ".java"},
+	{"javascript", "JavaScript", ".js"},
+	{"json", "JSON", ".json"},
+	{"markdown", "Markdown", ".md"},
+	{"php", "PHP", ".php"},
+	{"python", "Python", ".py"},
+	{"ruby", "Ruby", ".rb"},
+	{"rust", "Rust", ".rs"},
+	{"sql", "SQL", ".sql"},
+	{"toml", "TOML", ".toml"},
+	{"typescript", "TypeScript", ".ts"},
+	{"yaml", "YAML", ".yaml"},
+}
+
+// Lookup returns the Language with the given name, and whether there is one.
+func Lookup(name string) (Language, bool) {
+	for _, l := range Languages {
+		if l.Name == name {
+			return l, true
+		}
+	}
+
+	return Language{}, false
+}
+
+// Resolve returns the language a snippet should be highlighted as: the one
+// chosen by the user, or the detected one if they didn't choose.
+func Resolve(content, name string) Language {
+	if l, ok := Lookup(name); ok {
+		return l
+	}
+
+	l, _ := Lookup(Detect(content))
+
+	return l
+}
+
+// The detection rules are checked in order before falling back to chroma's
+// own analysers, since those only exist for a few languages.
+var detectRules = []struct {
+	rx   *regexp.Regexp
+	name string
+}{
+	{regexp.MustCompile(`^#!.*\b(ba|z)?sh\b`), "bash"},
+	{regexp.MustCompile(`^#!.*\bpython`), "python"},
+	{regexp.MustCompile(`^#!.*\b(node|deno)\b`), "javascript"},
+	{regexp.MustCompile(`^#!.*\bruby\b`), "ruby"},
+	{regexp.MustCompile(`^<\?php`), "php"},
+	{regexp.MustCompile(`(?i)^<!doctype html|^<html`), "html"},
+	{regexp.MustCompile(`(?m)^(diff --git|--- a/|\+\+\+ b/|@@ -\d+)`), "diff"},
+	{regexp.MustCompile(`(?m)^package \w+$`), "go"},
+	{regexp.MustCompile(`(?m)^FROM \S+`), "docker"},
+	{regexp.MustCompile(`(?m)^\s*(fn \w+\(|use std::|impl\b)`), "rust"},
+	{regexp.MustCompile(`(?m)^\s*(def \w+\(.*\):|from [\w.]+ import |import \w+$)`), "python"},
+	{regexp.MustCompile(`(?m)^#include\s*<`), "cpp"},
+	{regexp.MustCompile(`(?m)^\s*(public |private )?(class|interface) \w+.*\{`), "java"},
+	{regexp.MustCompile(`(?m)^\s*(const|let|var|function)\s+\w+|=>\s*\{|console\.log\(`), "javascript"},
+	{regexp.MustCompile(`(?im)^\s*(SELECT .+ FROM|INSERT INTO|CREATE TABLE|UPDATE \w+ SET)\b`), "sql"},
+}
+
+// Detect guesses the language of some content, returning the name of one of
+// the Languages. It returns PlainText if it can't tell.
+func Detect(content string) string {
+	trimmed := strings.TrimSpace(content)
+
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+
+	for _, rule := range detectRules {
+		if rule.rx.MatchString(trimmed) {
+			return rule.name
+		}
+	}
+
+	if lexer := lexers.Analyse(content); lexer != nil {
+		for _, l := range Languages {
+			if lexers.Get(l.Name) == lexer {
+				return l.Name
+			}
+		}
+	}
+
+	return PlainText
+}
+
+// The formatter adds line numbers which are links to the #L1, #L2, ...
+// anchors, and uses the L prefix for the ids of the lines so that they can be
+// targeted by those links.
+var formatter = html.New(
+	html.WithClasses(true),
+	html.WithLineNumbers(true),
+	html.WithLinkableLineNumbers(true, "L"),
+	html.TabWidth(4),
+)
+
+var style = styles.Get("github")
+
+// HTML returns the content highlighted as the named language (or the detected
+// language, if the name is empty), with line numbers. If highlighting fails
+// the content is returned as escaped plain text instead.
+func HTML(content, name string) template.HTML {
+	lexer := lexers.Get(Resolve(content, name).Name)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	// Coalesce runs of tokens of the same type, to keep the HTML smaller.
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, content)
+	if err != nil {
+		return template.HTML("<pre><code>" + template.HTMLEscapeString(content) + "</code></pre>")
+	}
+
+	var buf bytes.Buffer
+
+	err = formatter.Format(&buf, style, iterator)
+	if err != nil {
+		return template.HTML("<pre><code>" + template.HTMLEscapeString(content) + "</code></pre>")
+	}
+
+	return template.HTML(buf.String())
+}
+
+// CSS returns the stylesheet for the classes used in the highlighted HTML. It
+// is only generated once.
+var CSS = sync.OnceValue(func() []byte {
+	var buf bytes.Buffer
+
+	// Writing to a bytes.Buffer can't fail.
+	formatter.WriteCSS(&buf, style)
+
+	return buf.Bytes()
+})
@@ -0,0 +1,431 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The migration files for each database live in a directory named after the
+// database. Each migration is a pair of files named like
+// 0001_initial_schema.up.sql and 0001_initial_schema.down.sql, and they are
+// applied in order of their version number.
+//
+//go:embed mysql/*.sql sqlite/*.sql postgres/*.sql
+var files embed.FS
+
+var filenameRX = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// lockName is the name of the lock taken while migrations are running, so
+// that two copies of the application starting at the same time don't both
+// try to apply the same migrations.
+const lockName = "snippetbox_migrations"
+
+// lockID is the key for the PostgreSQL advisory lock, which must be a
+// number. It is an arbitrary constant.
+const lockID = 727_170_001
+
+// Define a Migration type to hold a single versioned schema change.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Define a Status type which describes whether a migration has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Define a Migrator type which applies the embedded migrations for one of the
+// supported database/sql drivers ("mysql", "sqlite" or "pgx").
+type Migrator struct {
+	DB         *sql.DB
+	Driver     string
+	migrations []Migration
+}
+
+// New returns a Migrator for the given database and driver, loaded with the
+// embedded migrations for that driver.
+func New(db *sql.DB, driver string) (*Migrator, error) {
+	dir, ok := map[string]string{"mysql": "mysql", "sqlite": "sqlite", "pgx": "postgres"}[driver]
+	if !ok {
+		return nil, fmt.Errorf("migrations: unsupported driver %q", driver)
+	}
+
+	migrations, err := load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{DB: db, Driver: driver, migrations: migrations}, nil
+}
+
+// load reads and parses the migration files in a directory of the embedded
+// filesystem, returning them sorted by version.
+func load(dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, entry := range entries {
+		matches := filenameRX.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("migrations: invalid file name %q", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(matches[1])
+
+		b, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		}
+
+		if m.Name != matches[2] {
+			return nil, fmt.Errorf("migrations: version %d has more than one name", version)
+		}
+
+		if matches[3] == "up" {
+			m.Up = string(b)
+			sum := sha256.Sum256(b)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	var migrations []Migration
+
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migrations: %s has no up migration", m)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies all the pending migrations in order, and returns the ones which
+// were applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		records, err := m.records(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := records[migration.Version]; ok {
+				continue
+			}
+
+			err = m.apply(ctx, conn, migration.Up, func(exec execer) error {
+				_, err := exec.ExecContext(ctx, m.query("INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)"),
+					migration.Version, migration.Name, migration.Checksum, time.Now().UTC())
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migrations: applying %s: %w", migration, err)
+			}
+
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down rolls back the most recently applied migration, and returns it. If no
+// migrations have been applied it returns nil.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var rolledBack *Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		records, err := m.records(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+
+			if _, ok := records[migration.Version]; !ok {
+				continue
+			}
+
+			if migration.Down == "" {
+				return fmt.Errorf("migrations: %s has no down migration", migration)
+			}
+
+			err = m.apply(ctx, conn, migration.Down, func(exec execer) error {
+				_, err := exec.ExecContext(ctx, m.query("DELETE FROM schema_migrations WHERE version = ?"), migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migrations: rolling back %s: %w", migration, err)
+			}
+
+			rolledBack = &migration
+			return nil
+		}
+
+		return nil
+	})
+
+	return rolledBack, err
+}
+
+// Status returns every known migration along with whether it has been
+// applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		records, err := m.records(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			appliedAt, ok := records[migration.Version]
+			statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// records creates the schema_migrations table if it doesn't exist yet, then
+// returns the time each applied migration was applied, keyed by version. It
+// returns an error if the checksum of an applied migration doesn't match the
+// embedded file, since that means the file was changed after it was run.
+func (m *Migrator) records(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	stmt := `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	checksum CHAR(64) NOT NULL,
+	applied_at TIMESTAMP NOT NULL)`
+
+	if m.Driver == "mysql" {
+		stmt = strings.Replace(stmt, "TIMESTAMP", "DATETIME", 1)
+	}
+
+	_, err := conn.ExecContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	records := make(map[int]time.Time)
+
+	for rows.Next() {
+		var (
+			version   int
+			checksum  string
+			appliedAt time.Time
+		)
+
+		err = rows.Scan(&version, &checksum, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		i := slices.IndexFunc(m.migrations, func(migration Migration) bool {
+			return migration.Version == version
+		})
+		if i >= 0 && m.migrations[i].Checksum != checksum {
+			return nil, fmt.Errorf("migrations: checksum mismatch for %s: the file has changed since it was applied", m.migrations[i])
+		}
+
+		records[version] = appliedAt
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// execer is the interface shared by *sql.Conn and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// apply executes the statements in a migration file followed by record, which
+// updates the schema_migrations table. On PostgreSQL both happen in a single
+// transaction, so a failed migration leaves no trace. MySQL commits DDL
+// statements implicitly, so a transaction wouldn't help there, and on SQLite
+// we are already inside the transaction started by withLock.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script string, record func(execer) error) error {
+	var exec execer = conn
+
+	if m.Driver == "pgx" {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		exec = tx
+	}
+
+	for _, stmt := range statements(script) {
+		_, err := exec.ExecContext(ctx, stmt)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := record(exec)
+	if err != nil {
+		return err
+	}
+
+	if tx, ok := exec.(*sql.Tx); ok {
+		return tx.Commit()
+	}
+
+	return nil
+}
+
+// withLock runs fn on a single connection while holding a lock which stops
+// other copies of the application from running migrations at the same time.
+// MySQL and PostgreSQL have named locks for this. SQLite doesn't, but only
+// allows one writer at a time, so we hold a write transaction instead.
+func (m *Migrator) withLock(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	switch m.Driver {
+	case "mysql":
+		var ok sql.NullInt64
+
+		err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 60)", lockName).Scan(&ok)
+		if err != nil {
+			return err
+		}
+		if ok.Int64 != 1 {
+			return errors.New("migrations: timed out waiting for the migration lock")
+		}
+		defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+
+	case "pgx":
+		_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID)
+		if err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+
+	case "sqlite":
+		_, err = conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+		if err != nil {
+			return err
+		}
+
+		err = fn(conn)
+		if err != nil {
+			conn.ExecContext(context.Background(), "ROLLBACK")
+			return err
+		}
+
+		_, err = conn.ExecContext(ctx, "COMMIT")
+		return err
+	}
+
+	return fn(conn)
+}
+
+// query rewrites the ? placeholders in a query into the $1, $2, ... style
+// when using PostgreSQL.
+func (m *Migrator) query(q string) string {
+	if m.Driver != "pgx" {
+		return q
+	}
+
+	for n := 1; strings.Contains(q, "?"); n++ {
+		q = strings.Replace(q, "?", "$"+strconv.Itoa(n), 1)
+	}
+
+	return q
+}
+
+// statements splits a migration file into its individual SQL statements,
+// since not all drivers can execute several statements in one call. Lines
+// starting with -- are comments, and a statement ends with a semicolon at the
+// end of a line.
+func statements(script string) []string {
+	var (
+		stmts   []string
+		current strings.Builder
+	)
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+
+	if s := strings.TrimSpace(current.String()); s != "" {
+		stmts = append(stmts, s)
+	}
+
+	return stmts
+}
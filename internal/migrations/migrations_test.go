@@ -0,0 +1,143 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+func TestStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"Empty", "", nil},
+		{"Comments only", "-- nothing to see\n\n", nil},
+		{"One statement", "CREATE TABLE a (id INT);\n", []string{"CREATE TABLE a (id INT)"}},
+		{
+			"Several lines",
+			"-- Create a table.\nCREATE TABLE a (\n    id INT\n);\n\nCREATE INDEX a_id ON a(id);\n",
+			[]string{"CREATE TABLE a (\n    id INT\n)", "CREATE INDEX a_id ON a(id)"},
+		},
+		{"No final semicolon", "DROP TABLE a", []string{"DROP TABLE a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statements(tt.script); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMigrator applies every migration to a new SQLite database, rolls them
+// all back, then applies them again. The MySQL and PostgreSQL test databases
+// (see internal/models) are only migrated up, since they are shared with the
+// models tests which may be running at the same time.
+func TestMigrator(t *testing.T) {
+	dsns := []struct {
+		driver string
+		dsn    string
+	}{
+		{"sqlite", "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=foreign_keys(1)"},
+		{"mysql", os.Getenv("SNIPPETBOX_TEST_MYSQL_DSN")},
+		{"pgx", os.Getenv("SNIPPETBOX_TEST_POSTGRES_DSN")},
+	}
+
+	for _, d := range dsns {
+		t.Run(d.driver, func(t *testing.T) {
+			if d.dsn == "" {
+				t.Skipf("no test database for %s", d.driver)
+			}
+
+			db, err := sql.Open(d.driver, d.dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			m, err := New(db, d.driver)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+
+			_, err = m.Up(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			applied, err := m.Up(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(applied) != 0 {
+				t.Errorf("got %d migrations applied a second time; want none", len(applied))
+			}
+
+			checkApplied(t, m, true)
+
+			if d.driver != "sqlite" {
+				return
+			}
+
+			for i := len(m.migrations) - 1; i >= 0; i-- {
+				rolledBack, err := m.Down(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if rolledBack == nil || rolledBack.Version != m.migrations[i].Version {
+					t.Fatalf("got %v rolled back; want %s", rolledBack, m.migrations[i])
+				}
+			}
+
+			rolledBack, err := m.Down(ctx)
+			if err != nil || rolledBack != nil {
+				t.Fatalf("got %v and error %v with nothing to roll back; want nil", rolledBack, err)
+			}
+
+			checkApplied(t, m, false)
+
+			applied, err = m.Up(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(applied) != len(m.migrations) {
+				t.Errorf("got %d migrations applied again; want %d", len(applied), len(m.migrations))
+			}
+		})
+	}
+}
+
+// checkApplied checks that every migration has or hasn't been applied.
+func checkApplied(t *testing.T, m *Migrator, want bool) {
+	t.Helper()
+
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(statuses) != len(m.migrations) {
+		t.Fatalf("got %d statuses; want %d", len(statuses), len(m.migrations))
+	}
+
+	for _, s := range statuses {
+		if s.Applied != want || s.AppliedAt.IsZero() == want {
+			t.Errorf("got %s applied %t at %s; want applied %t", s.Migration, s.Applied, s.AppliedAt, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+-- The initial schema. Tables are created with IF NOT EXISTS so that databases
+-- which were set up by hand before migrations existed can be brought under
+-- version control by running this migration.
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    hashed_password CHAR(60) NOT NULL,
+    created DATETIME NOT NULL,
+    CONSTRAINT users_uc_email UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS snippets (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    user_id INTEGER NULL,
+    delete_key_hash CHAR(64) NULL,
+    INDEX idx_snippets_created (created),
+    CONSTRAINT snippets_fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    token CHAR(43) PRIMARY KEY,
+    data BLOB NOT NULL,
+    expiry TIMESTAMP(6) NOT NULL,
+    INDEX sessions_expiry_idx (expiry)
+);
+
+CREATE TABLE IF NOT EXISTS tokens (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    scope VARCHAR(16) NOT NULL,
+    created DATETIME NOT NULL,
+    last_used DATETIME NULL,
+    CONSTRAINT tokens_uc_hash UNIQUE (hash),
+    CONSTRAINT tokens_fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
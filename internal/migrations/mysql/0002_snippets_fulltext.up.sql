@@ -0,0 +1,2 @@
+-- Add a full-text index used by SnippetModel.Search().
+ALTER TABLE snippets ADD FULLTEXT INDEX snippets_ft_title_content (title, content);
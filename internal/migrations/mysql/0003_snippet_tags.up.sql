@@ -0,0 +1,16 @@
+-- Tags are stored once in the tags table, and linked to snippets through the
+-- snippet_tags table. Deleting a snippet removes its links, but not the tags.
+CREATE TABLE tags (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(30) NOT NULL,
+    CONSTRAINT tags_uc_name UNIQUE (name)
+);
+
+CREATE TABLE snippet_tags (
+    snippet_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (snippet_id, tag_id),
+    INDEX snippet_tags_tag_id_idx (tag_id),
+    CONSTRAINT snippet_tags_fk_snippet_id FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE,
+    CONSTRAINT snippet_tags_fk_tag_id FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
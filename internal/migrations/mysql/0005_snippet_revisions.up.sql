@@ -0,0 +1,20 @@
+-- Every saved version of a snippet's title, content and language is kept in
+-- the snippet_revisions table, numbered from 1 for each snippet. user_id is
+-- the author of the revision, or NULL for anonymous snippets.
+CREATE TABLE snippet_revisions (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    language VARCHAR(30) NOT NULL DEFAULT '',
+    user_id INTEGER NULL,
+    created DATETIME NOT NULL,
+    CONSTRAINT snippet_revisions_uc_version UNIQUE (snippet_id, version),
+    CONSTRAINT snippet_revisions_fk_snippet_id FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE,
+    CONSTRAINT snippet_revisions_fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Existing snippets start with their current state as revision 1.
+INSERT INTO snippet_revisions (snippet_id, version, title, content, language, user_id, created)
+SELECT id, 1, title, content, language, user_id, created FROM snippets;
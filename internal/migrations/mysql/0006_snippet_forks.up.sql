@@ -0,0 +1,4 @@
+-- The snippet which a snippet was forked from, or NULL if it isn't a fork.
+-- Forks are kept when the original is deleted, but lose the link to it.
+ALTER TABLE snippets ADD COLUMN parent_id INTEGER NULL;
+ALTER TABLE snippets ADD CONSTRAINT snippets_fk_parent_id FOREIGN KEY (parent_id) REFERENCES snippets(id) ON DELETE SET NULL;
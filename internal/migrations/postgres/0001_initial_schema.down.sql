@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS snippets;
+DROP TABLE IF EXISTS users;
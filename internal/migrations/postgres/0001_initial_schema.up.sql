@@ -0,0 +1,42 @@
+-- The initial schema. Tables are created with IF NOT EXISTS so that databases
+-- which were set up by hand before migrations existed can be brought under
+-- version control by running this migration.
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    hashed_password CHAR(60) NOT NULL,
+    created TIMESTAMP(0) NOT NULL,
+    CONSTRAINT users_uc_email UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS snippets (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created TIMESTAMP(0) NOT NULL,
+    expires TIMESTAMP(0) NOT NULL,
+    user_id INTEGER NULL REFERENCES users(id) ON DELETE SET NULL,
+    delete_key_hash CHAR(64) NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_snippets_created ON snippets(created);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    token TEXT PRIMARY KEY,
+    data BYTEA NOT NULL,
+    expiry TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_expiry_idx ON sessions(expiry);
+
+CREATE TABLE IF NOT EXISTS tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    scope VARCHAR(16) NOT NULL,
+    created TIMESTAMP(0) NOT NULL,
+    last_used TIMESTAMP NULL,
+    CONSTRAINT tokens_uc_hash UNIQUE (hash)
+);
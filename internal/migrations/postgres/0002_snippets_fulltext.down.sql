@@ -0,0 +1 @@
+DROP INDEX IF EXISTS snippets_search_idx;
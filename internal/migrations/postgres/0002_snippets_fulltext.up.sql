@@ -0,0 +1,4 @@
+-- Add a full-text index used by PostgresSnippetModel.Search(). The expression
+-- must match the one used in the query exactly for the index to be used.
+CREATE INDEX snippets_search_idx ON snippets
+    USING GIN (to_tsvector('english', title || ' ' || content));
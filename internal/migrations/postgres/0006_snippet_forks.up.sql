@@ -0,0 +1,5 @@
+-- The snippet which a snippet was forked from, or NULL if it isn't a fork.
+-- Forks are kept when the original is deleted, but lose the link to it.
+ALTER TABLE snippets ADD COLUMN parent_id INTEGER NULL REFERENCES snippets(id) ON DELETE SET NULL;
+
+CREATE INDEX snippets_parent_id_idx ON snippets(parent_id);
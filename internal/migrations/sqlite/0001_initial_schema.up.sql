@@ -0,0 +1,42 @@
+-- The initial schema. Tables are created with IF NOT EXISTS so that databases
+-- which were set up by hand before migrations existed can be brought under
+-- version control by running this migration.
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    email TEXT NOT NULL,
+    hashed_password TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    CONSTRAINT users_uc_email UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS snippets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    user_id INTEGER NULL REFERENCES users(id) ON DELETE SET NULL,
+    delete_key_hash TEXT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_snippets_created ON snippets(created);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    token TEXT PRIMARY KEY,
+    data BLOB NOT NULL,
+    expiry REAL NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_expiry_idx ON sessions(expiry);
+
+CREATE TABLE IF NOT EXISTS tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    last_used DATETIME NULL,
+    CONSTRAINT tokens_uc_hash UNIQUE (hash)
+);
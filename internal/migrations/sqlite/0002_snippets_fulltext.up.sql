@@ -0,0 +1,3 @@
+-- SQLite searches snippets with LIKE, so there is no full-text index. This
+-- migration is empty to keep the version numbers in step with the other
+-- databases.
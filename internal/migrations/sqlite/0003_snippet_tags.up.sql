@@ -0,0 +1,15 @@
+-- Tags are stored once in the tags table, and linked to snippets through the
+-- snippet_tags table. Deleting a snippet removes its links, but not the tags.
+CREATE TABLE tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    CONSTRAINT tags_uc_name UNIQUE (name)
+);
+
+CREATE TABLE snippet_tags (
+    snippet_id INTEGER NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (snippet_id, tag_id)
+);
+
+CREATE INDEX snippet_tags_tag_id_idx ON snippet_tags(tag_id);
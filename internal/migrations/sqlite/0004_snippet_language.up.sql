@@ -0,0 +1,3 @@
+-- The language used to highlight a snippet. An empty string means that it is
+-- detected from the content when the snippet is displayed.
+ALTER TABLE snippets ADD COLUMN language TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,18 @@
+-- Every saved version of a snippet's title, content and language is kept in
+-- the snippet_revisions table, numbered from 1 for each snippet. user_id is
+-- the author of the revision, or NULL for anonymous snippets.
+CREATE TABLE snippet_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    snippet_id INTEGER NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    language TEXT NOT NULL DEFAULT '',
+    user_id INTEGER NULL REFERENCES users(id) ON DELETE SET NULL,
+    created DATETIME NOT NULL,
+    CONSTRAINT snippet_revisions_uc_version UNIQUE (snippet_id, version)
+);
+
+-- Existing snippets start with their current state as revision 1.
+INSERT INTO snippet_revisions (snippet_id, version, title, content, language, user_id, created)
+SELECT id, 1, title, content, language, user_id, created FROM snippets;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS snippets_parent_id_idx;
+ALTER TABLE snippets DROP COLUMN parent_id;
@@ -2,4 +2,18 @@ package models
 
 import "errors"
 
-var ErrNoRecord = errors.New("models: no matching record found")
+var (
+	ErrNoRecord = errors.New("models: no matching record found")
+
+	// Add a new ErrInvalidCredentials error. We'll use this later if a user
+	// tries to login with an incorrect email address or password.
+	ErrInvalidCredentials = errors.New("models: invalid credentials")
+
+	// Add a new ErrDuplicateEmail error. We'll use this later if a user
+	// tries to signup with an email address that's already in use.
+	ErrDuplicateEmail = errors.New("models: duplicate email")
+
+	// Add a new ErrInvalidCursor error, for when the cursor passed to List()
+	// wasn't one that we created (for example, if a user edited the URL).
+	ErrInvalidCursor = errors.New("models: invalid cursor")
+)
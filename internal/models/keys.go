@@ -0,0 +1,33 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// generateSecret returns a new random secret (such as a snippet delete key or
+// an API token), along with the SHA-256 hash of it. Only the hash is stored in
+// the database; the plain-text secret is shown to its owner once and never
+// again.
+func generateSecret() (string, string, error) {
+	b := make([]byte, 24)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", "", err
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(b)
+
+	return secret, hashSecret(secret), nil
+}
+
+// hashSecret returns the hex-encoded SHA-256 hash of a secret. The secrets are
+// long random strings, so a fast hash is sufficient (unlike passwords, which
+// we hash with bcrypt).
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
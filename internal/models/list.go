@@ -0,0 +1,237 @@
+package models
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The orders in which the SnippetStore List() method can return snippets.
+const (
+	SortNewest   = "newest"
+	SortExpiring = "expiring"
+	SortTitle    = "title"
+)
+
+// ListPageSize is the number of snippets in each page returned by the
+// SnippetStore List() method.
+const ListPageSize = 20
+
+// Define a ListOptions type to hold the parameters for listing snippets. Sort
+// is one of the Sort constants (newest first if it is empty). At most one of
+// After and Before should be set, to a cursor from a previous SnippetPage. If
+// Tag is set, only snippets with that tag are listed.
+type ListOptions struct {
+	Sort   string
+	After  string
+	Before string
+	Tag    string
+}
+
+// Define a SnippetPage type to hold one page of a listing. Next and Prev are
+// opaque cursors to pass as ListOptions.After and ListOptions.Before to fetch
+// the following and preceding pages. They are empty if there is no such page.
+type SnippetPage struct {
+	Snippets []Snippet
+	Next     string
+	Prev     string
+}
+
+// listSort describes how to order the snippets for one of the Sort
+// constants. The snippet ID is always used as a tie-breaker, so that every
+// snippet has a unique position and a cursor can point at it.
+type listSort struct {
+	column  string
+	desc    bool
+	compare func(a, b Snippet) int
+}
+
+var listSorts = map[string]listSort{
+	SortNewest: {
+		desc: true,
+		compare: func(a, b Snippet) int {
+			return cmp.Compare(a.ID, b.ID)
+		},
+	},
+	SortExpiring: {
+		column: "expires",
+		compare: func(a, b Snippet) int {
+			return cmp.Or(a.Expires.Compare(b.Expires), cmp.Compare(a.ID, b.ID))
+		},
+	},
+	SortTitle: {
+		column: "title",
+		compare: func(a, b Snippet) int {
+			return cmp.Or(strings.Compare(a.Title, b.Title), cmp.Compare(a.ID, b.ID))
+		},
+	},
+}
+
+// lookupSort returns the listSort for opts.Sort, defaulting to newest first.
+func lookupSort(opts ListOptions) (listSort, error) {
+	if opts.Sort == "" {
+		return listSorts[SortNewest], nil
+	}
+
+	sort, ok := listSorts[opts.Sort]
+	if !ok {
+		return listSort{}, fmt.Errorf("models: unknown sort order %q", opts.Sort)
+	}
+
+	return sort, nil
+}
+
+// A cursor holds the sort key of the snippet at the edge of a page. It is
+// encoded as base64 JSON, so that it can be used in a URL.
+type cursor struct {
+	ID      int       `json:"id"`
+	Title   string    `json:"title,omitempty"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+func encodeCursor(sort string, s Snippet) string {
+	c := cursor{ID: s.ID}
+
+	switch sort {
+	case SortExpiring:
+		c.Expires = s.Expires
+	case SortTitle:
+		c.Title = s.Title
+	}
+
+	b, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor returns a Snippet holding just the sort key from a cursor, so
+// that it can be compared with other snippets.
+func decodeCursor(s string) (Snippet, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Snippet{}, ErrInvalidCursor
+	}
+
+	var c cursor
+
+	err = json.Unmarshal(b, &c)
+	if err != nil || c.ID < 1 {
+		return Snippet{}, ErrInvalidCursor
+	}
+
+	return Snippet{ID: c.ID, Title: c.Title, Expires: c.Expires}, nil
+}
+
+// listQuery builds the SELECT statement for one page of a listing of the
+// unexpired snippets, using ? placeholders. now is the SQL expression for the
+// current UTC time, and timeArg converts a time to a query argument for the
+// expires column. The statement fetches one more snippet than fits on a page,
+// so that we can tell whether there is another page. When paging backwards
+// the snippets come back in reverse order, and newPage puts them right.
+func listQuery(opts ListOptions, now string, timeArg func(time.Time) any) (string, []any, error) {
+	sort, err := lookupSort(opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	stmt := `SELECT id, title, content, language, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE expires > ` + now
+
+	var args []any
+
+	if opts.Tag != "" {
+		stmt += tagFilter
+		args = append(args, opts.Tag)
+	}
+
+	asc := !sort.desc
+	key := opts.After
+
+	if opts.Before != "" {
+		asc = !asc
+		key = opts.Before
+	}
+
+	op, dir := "<", "DESC"
+	if asc {
+		op, dir = ">", "ASC"
+	}
+
+	if key != "" {
+		c, err := decodeCursor(key)
+		if err != nil {
+			return "", nil, err
+		}
+
+		var value any
+
+		switch sort.column {
+		case "expires":
+			value = timeArg(c.Expires)
+		case "title":
+			value = c.Title
+		}
+
+		if sort.column == "" {
+			stmt += fmt.Sprintf(" AND id %s ?", op)
+			args = append(args, c.ID)
+		} else {
+			stmt += fmt.Sprintf(" AND (%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", sort.column, op)
+			args = append(args, value, value, c.ID)
+		}
+	}
+
+	if sort.column != "" {
+		stmt += fmt.Sprintf(" ORDER BY %s %s,", sort.column, dir)
+	} else {
+		stmt += " ORDER BY"
+	}
+
+	stmt += fmt.Sprintf(" id %s LIMIT ?", dir)
+	args = append(args, ListPageSize+1)
+
+	return stmt, args, nil
+}
+
+// newPage turns the snippets fetched by a listQuery (or the in-memory
+// equivalent) into a SnippetPage, working out the cursors for the
+// neighbouring pages.
+func newPage(opts ListOptions, snippets []Snippet) SnippetPage {
+	more := len(snippets) > ListPageSize
+	if more {
+		snippets = snippets[:ListPageSize]
+	}
+
+	sort := cmp.Or(opts.Sort, SortNewest)
+	page := SnippetPage{Snippets: snippets}
+
+	if len(snippets) == 0 {
+		return page
+	}
+
+	first, last := snippets[0], snippets[len(snippets)-1]
+
+	if opts.Before != "" {
+		slices.Reverse(snippets)
+		first, last = last, first
+
+		// We came from the next page, so there is one.
+		page.Next = encodeCursor(sort, last)
+		if more {
+			page.Prev = encodeCursor(sort, first)
+		}
+	} else {
+		if more {
+			page.Next = encodeCursor(sort, last)
+		}
+		if opts.After != "" {
+			page.Prev = encodeCursor(sort, first)
+		}
+	}
+
+	return page
+}
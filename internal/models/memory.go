@@ -0,0 +1,401 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memorySnippet is a stored snippet along with the hash of its delete key
+// and its revisions, oldest first.
+type memorySnippet struct {
+	Snippet
+	deleteKeyHash string
+	revisions     []Revision
+}
+
+// Define a MemorySnippetModel type which keeps snippets in memory instead of
+// a database. It is safe for concurrent use, but the snippets are lost when
+// the application exits.
+type MemorySnippetModel struct {
+	mu       sync.RWMutex
+	snippets map[int]memorySnippet
+	lastID   int
+}
+
+// hasTag reports whether the snippet has the given tag. Every snippet matches
+// an empty tag.
+func (s memorySnippet) hasTag(tag string) bool {
+	return tag == "" || slices.Contains(s.Tags, tag)
+}
+
+// snippet returns a copy of the stored snippet, with its own tags slice so
+// that callers can't change the stored ones.
+func (s memorySnippet) snippet() Snippet {
+	snippet := s.Snippet
+	snippet.Tags = slices.Clone(s.Tags)
+	return snippet
+}
+
+// addRevision records the snippet's current title, content and language as
+// its next revision, unless they are the same as the latest revision.
+func (s *memorySnippet) addRevision(userID int, created time.Time) {
+	if n := len(s.revisions); n > 0 {
+		latest := s.revisions[n-1]
+		if latest.Title == s.Title && latest.Content == s.Content && latest.Language == s.Language {
+			return
+		}
+	}
+
+	s.revisions = append(s.revisions, Revision{
+		SnippetID: s.ID,
+		Version:   len(s.revisions) + 1,
+		Title:     s.Title,
+		Content:   s.Content,
+		Language:  s.Language,
+		UserID:    userID,
+		Created:   created,
+	})
+}
+
+// orphanForks removes the link from the forks of a deleted snippet to it,
+// like the ON DELETE SET NULL constraint on the parent_id column. The caller
+// must hold the write lock.
+func (m *MemorySnippetModel) orphanForks(id int) {
+	for forkID, s := range m.snippets {
+		if s.ParentID == id {
+			s.ParentID = 0
+			m.snippets[forkID] = s
+		}
+	}
+}
+
+// NewMemorySnippetModel returns a new, empty MemorySnippetModel.
+func NewMemorySnippetModel() *MemorySnippetModel {
+	return &MemorySnippetModel{
+		snippets: make(map[int]memorySnippet),
+	}
+}
+
+// now returns the current UTC time truncated to whole seconds, to match the
+// precision of UTC_TIMESTAMP() and the DATETIME columns in MySQL.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// This will insert a new snippet into the store.
+func (m *MemorySnippetModel) Insert(title string, content string, language string, expires int, userID int, parentID int, tags []string) (int, string, error) {
+	key, hash, err := generateSecret()
+	if err != nil {
+		return 0, "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastID++
+
+	created := now()
+
+	s := memorySnippet{
+		Snippet: Snippet{
+			ID:       m.lastID,
+			Title:    title,
+			Content:  content,
+			Language: language,
+			Created:  created,
+			Expires:  created.AddDate(0, 0, expires),
+			UserID:   userID,
+			Tags:     slices.Clone(tags),
+			ParentID: parentID,
+		},
+		deleteKeyHash: hash,
+	}
+	s.addRevision(userID, created)
+
+	m.snippets[m.lastID] = s
+
+	return m.lastID, key, nil
+}
+
+// This will return a specific snippet based on its id, unless it has expired.
+func (m *MemorySnippetModel) Get(id int) (Snippet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.snippets[id]
+	if !ok || !s.Expires.After(now()) {
+		return Snippet{}, ErrNoRecord
+	}
+
+	return s.snippet(), nil
+}
+
+// This will return the 10 most recently created snippets which haven't
+// expired, only including those with the given tag unless it is empty.
+func (m *MemorySnippetModel) Latest(tag string) ([]Snippet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t := now()
+
+	var snippets []Snippet
+
+	for _, s := range m.snippets {
+		if s.Expires.After(t) && s.hasTag(tag) {
+			snippets = append(snippets, s.snippet())
+		}
+	}
+
+	// Sort by descending ID, the same as ORDER BY id DESC.
+	slices.SortFunc(snippets, func(a, b Snippet) int {
+		return b.ID - a.ID
+	})
+
+	if len(snippets) > 10 {
+		snippets = snippets[:10]
+	}
+
+	return snippets, nil
+}
+
+// This will return a page of the unexpired snippets in the order given by
+// opts.Sort, using the same cursors as the database stores.
+func (m *MemorySnippetModel) List(opts ListOptions) (SnippetPage, error) {
+	sort, err := lookupSort(opts)
+	if err != nil {
+		return SnippetPage{}, err
+	}
+
+	compare := sort.compare
+	if sort.desc {
+		compare = func(a, b Snippet) int { return sort.compare(b, a) }
+	}
+
+	key := opts.After
+	if opts.Before != "" {
+		// Walk backwards from the cursor, like the database query does.
+		forward := compare
+		compare = func(a, b Snippet) int { return forward(b, a) }
+		key = opts.Before
+	}
+
+	var c Snippet
+	if key != "" {
+		c, err = decodeCursor(key)
+		if err != nil {
+			return SnippetPage{}, err
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t := now()
+
+	var snippets []Snippet
+
+	for _, s := range m.snippets {
+		if s.Expires.After(t) && s.hasTag(opts.Tag) && (key == "" || compare(s.Snippet, c) > 0) {
+			snippets = append(snippets, s.snippet())
+		}
+	}
+
+	slices.SortFunc(snippets, compare)
+
+	if len(snippets) > ListPageSize+1 {
+		snippets = snippets[:ListPageSize+1]
+	}
+
+	return newPage(opts, snippets), nil
+}
+
+// This will update the title, content and expiry of an existing snippet,
+// keeping the current expiry if expires is 0.
+func (m *MemorySnippetModel) Update(id int, title string, content string, language string, expires int, userID int, tags []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := now()
+
+	s, ok := m.snippets[id]
+	if !ok || !s.Expires.After(t) {
+		return ErrNoRecord
+	}
+
+	s.Title = title
+	s.Content = content
+	s.Language = language
+	if expires != 0 {
+		s.Expires = t.AddDate(0, 0, expires)
+	}
+	s.Tags = slices.Clone(tags)
+	s.addRevision(userID, t)
+	m.snippets[id] = s
+
+	return nil
+}
+
+// This will delete a specific snippet based on its id.
+func (m *MemorySnippetModel) Delete(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.snippets[id]; !ok {
+		return ErrNoRecord
+	}
+
+	delete(m.snippets, id)
+	m.orphanForks(id)
+
+	return nil
+}
+
+// This will delete a specific snippet if the given delete key matches.
+func (m *MemorySnippetModel) DeleteWithKey(id int, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.snippets[id]
+	if !ok || s.deleteKeyHash != hashSecret(key) {
+		return ErrInvalidCredentials
+	}
+
+	delete(m.snippets, id)
+	m.orphanForks(id)
+
+	return nil
+}
+
+// This will permanently delete up to limit snippets which have expired, and
+// return the number deleted.
+func (m *MemorySnippetModel) DeleteExpired(limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := now()
+	deleted := 0
+
+	for id, s := range m.snippets {
+		if deleted >= limit {
+			break
+		}
+
+		if !s.Expires.After(t) {
+			delete(m.snippets, id)
+			m.orphanForks(id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// This will return a page of the unexpired snippets whose title or content
+// contains every word of the search query (ignoring case), newest first, like
+// the SQLite LIKE search. If tag isn't empty, only snippets with that tag are
+// included.
+func (m *MemorySnippetModel) Search(query string, tag string, page int) ([]Snippet, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t := now()
+	terms := searchTerms(query)
+
+	var matches []Snippet
+
+	for _, s := range m.snippets {
+		if !s.Expires.After(t) || !s.hasTag(tag) {
+			continue
+		}
+
+		title, content := strings.ToLower(s.Title), strings.ToLower(s.Content)
+
+		ok := true
+		for _, term := range terms {
+			term = strings.ToLower(term)
+			if !strings.Contains(title, term) && !strings.Contains(content, term) {
+				ok = false
+				break
+			}
+		}
+
+		if ok {
+			matches = append(matches, s.snippet())
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b Snippet) int {
+		return b.ID - a.ID
+	})
+
+	offset := searchOffset(page)
+	if offset >= len(matches) {
+		return nil, false, nil
+	}
+
+	matches = matches[offset:min(len(matches), offset+SearchPageSize+1)]
+
+	snippets, more := trimPage(matches)
+
+	return snippets, more, nil
+}
+
+// This will return the unexpired snippets which were forked from a snippet,
+// newest first.
+func (m *MemorySnippetModel) Forks(id int) ([]Snippet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t := now()
+
+	var forks []Snippet
+
+	for _, s := range m.snippets {
+		if s.ParentID == id && s.Expires.After(t) {
+			forks = append(forks, s.snippet())
+		}
+	}
+
+	slices.SortFunc(forks, func(a, b Snippet) int {
+		return b.ID - a.ID
+	})
+
+	return forks, nil
+}
+
+// This will return the revisions of a snippet, newest first.
+func (m *MemorySnippetModel) Revisions(id int) ([]Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	revisions := slices.Clone(m.snippets[id].revisions)
+	slices.Reverse(revisions)
+
+	return revisions, nil
+}
+
+// This will make an earlier revision of an unexpired snippet current again,
+// recording it as a new revision by userID.
+func (m *MemorySnippetModel) Restore(id int, version int, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := now()
+
+	s, ok := m.snippets[id]
+	if !ok || !s.Expires.After(t) || version < 1 || version > len(s.revisions) {
+		return ErrNoRecord
+	}
+
+	r := s.revisions[version-1]
+
+	s.Title = r.Title
+	s.Content = r.Content
+	s.Language = r.Language
+	s.addRevision(userID, t)
+	m.snippets[id] = s
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"slices"
+	"sync"
+)
+
+// memoryToken is a stored API token along with the hash of the plain-text
+// token.
+type memoryToken struct {
+	Token
+	hash string
+}
+
+// Define a MemoryTokenModel type which keeps API tokens in memory instead of
+// a database, so that the handlers can be tested without one.
+type MemoryTokenModel struct {
+	mu     sync.Mutex
+	tokens map[int]memoryToken
+	lastID int
+}
+
+// NewMemoryTokenModel returns a new, empty MemoryTokenModel.
+func NewMemoryTokenModel() *MemoryTokenModel {
+	return &MemoryTokenModel{
+		tokens: make(map[int]memoryToken),
+	}
+}
+
+// This will create a new API token for a user and return the plain-text
+// token. Only a hash of it is kept.
+func (m *MemoryTokenModel) Insert(userID int, name string, scope string) (string, error) {
+	token, hash, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastID++
+
+	m.tokens[m.lastID] = memoryToken{
+		Token: Token{
+			ID:      m.lastID,
+			UserID:  userID,
+			Name:    name,
+			Scope:   scope,
+			Created: now(),
+		},
+		hash: hash,
+	}
+
+	return token, nil
+}
+
+// This will return all the API tokens belonging to a user, newest first.
+func (m *MemoryTokenModel) List(userID int) ([]Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tokens []Token
+
+	for _, t := range m.tokens {
+		if t.UserID == userID {
+			tokens = append(tokens, t.Token)
+		}
+	}
+
+	slices.SortFunc(tokens, func(a, b Token) int {
+		return b.ID - a.ID
+	})
+
+	return tokens, nil
+}
+
+// This will revoke one of a user's API tokens. If the user has no token with
+// that id we return the ErrNoRecord error.
+func (m *MemoryTokenModel) Revoke(id int, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.tokens[id]
+	if !ok || t.UserID != userID {
+		return ErrNoRecord
+	}
+
+	delete(m.tokens, id)
+
+	return nil
+}
+
+// This will look up the token matching a plain-text API token and record
+// that it has just been used. If there is no such token we return the
+// ErrInvalidCredentials error.
+func (m *MemoryTokenModel) Authenticate(token string) (Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hash := hashSecret(token)
+
+	for id, t := range m.tokens {
+		if t.hash != hash {
+			continue
+		}
+
+		// Return the token as it was before this use, like TokenModel.
+		found := t.Token
+
+		lastUsed := now()
+		t.LastUsed = &lastUsed
+		m.tokens[id] = t
+
+		return found, nil
+	}
+
+	return Token{}, ErrInvalidCredentials
+}
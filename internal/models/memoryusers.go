@@ -0,0 +1,102 @@
+package models
+
+import (
+	"errors"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Define a MemoryUserModel type which keeps user accounts in memory instead
+// of a database, so that the handlers can be tested without one. Like
+// MemorySnippetModel, the users are lost when the application exits.
+type MemoryUserModel struct {
+	mu     sync.RWMutex
+	users  map[int]User
+	lastID int
+}
+
+// NewMemoryUserModel returns a new, empty MemoryUserModel.
+func NewMemoryUserModel() *MemoryUserModel {
+	return &MemoryUserModel{
+		users: make(map[int]User),
+	}
+}
+
+// This will add a new user, returning the ErrDuplicateEmail error if the
+// email address is already in use.
+func (m *MemoryUserModel) Insert(name, email, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Email == email {
+			return ErrDuplicateEmail
+		}
+	}
+
+	m.lastID++
+
+	m.users[m.lastID] = User{
+		ID:             m.lastID,
+		Name:           name,
+		Email:          email,
+		HashedPassword: hashedPassword,
+		Created:        now(),
+	}
+
+	return nil
+}
+
+// This will return the ID of the user with the given email address and
+// password, or the ErrInvalidCredentials error if there isn't one.
+func (m *MemoryUserModel) Authenticate(email, password string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, u := range m.users {
+		if u.Email != email {
+			continue
+		}
+
+		err := bcrypt.CompareHashAndPassword(u.HashedPassword, []byte(password))
+		if err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return 0, ErrInvalidCredentials
+			}
+			return 0, err
+		}
+
+		return u.ID, nil
+	}
+
+	return 0, ErrInvalidCredentials
+}
+
+// This will check whether a user exists with a specific ID.
+func (m *MemoryUserModel) Exists(id int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.users[id]
+	return ok, nil
+}
+
+// This will return the user with the given ID, or the ErrNoRecord error if
+// there isn't one.
+func (m *MemoryUserModel) Get(id int) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.users[id]
+	if !ok {
+		return User{}, ErrNoRecord
+	}
+
+	return u, nil
+}
@@ -0,0 +1,375 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Define a PostgresSnippetModel type which wraps a sql.DB connection pool for
+// a PostgreSQL database. It behaves exactly like the MySQL SnippetModel. The
+// created and expires columns are TIMESTAMP(0) (without time zone) holding
+// UTC times, just like the MySQL DATETIME columns, so we use
+// now() AT TIME ZONE 'utc' in place of UTC_TIMESTAMP().
+type PostgresSnippetModel struct {
+	DB *sql.DB
+}
+
+// This will insert a new snippet into the database. PostgreSQL doesn't
+// support LastInsertId(), so we use a RETURNING clause to get the new ID.
+func (m *PostgresSnippetModel) Insert(title string, content string, language string, expires int, userID int, parentID int, tags []string) (int, string, error) {
+	stmt := `INSERT INTO snippets (title, content, language, created, expires, user_id, parent_id, delete_key_hash)
+	VALUES($1, $2, $3, now() AT TIME ZONE 'utc', (now() AT TIME ZONE 'utc') + make_interval(days => $4), $5, $6, $7)
+	RETURNING id`
+
+	key, hash, err := generateSecret()
+	if err != nil {
+		return 0, "", err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	var id int
+
+	err = tx.QueryRow(stmt, title, content, language, expires, nullableID(userID), nullableID(parentID), hash).Scan(&id)
+	if err != nil {
+		return 0, "", err
+	}
+
+	err = setTags(tx, "pgx", id, tags)
+	if err != nil {
+		return 0, "", err
+	}
+
+	err = addRevision(tx, "pgx", id, userID, title, content, language)
+	if err != nil {
+		return 0, "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, "", err
+	}
+
+	return id, key, nil
+}
+
+// This will return a specific snippet based on its id.
+func (m *PostgresSnippetModel) Get(id int) (Snippet, error) {
+	stmt := `SELECT id, title, content, language, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE expires > now() AT TIME ZONE 'utc' AND id = $1`
+
+	var s Snippet
+
+	err := m.DB.QueryRow(stmt, id).Scan(&s.ID, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires, &s.UserID, &s.ParentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Snippet{}, ErrNoRecord
+		} else {
+			return Snippet{}, err
+		}
+	}
+
+	snippets := []Snippet{s}
+
+	err = loadTags(m.DB, "pgx", snippets)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	return snippets[0], nil
+}
+
+// This will return the 10 most recently created snippets, only including
+// those with the given tag unless it is empty.
+func (m *PostgresSnippetModel) Latest(tag string) ([]Snippet, error) {
+	stmt := `SELECT id, title, content, language, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE expires > now() AT TIME ZONE 'utc'`
+
+	var args []any
+
+	if tag != "" {
+		stmt += tagFilter
+		args = append(args, tag)
+	}
+
+	stmt += ` ORDER BY id DESC LIMIT 10`
+
+	rows, err := m.DB.Query(rebind("pgx", stmt), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var snippets []Snippet
+
+	for rows.Next() {
+		var s Snippet
+
+		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires, &s.UserID, &s.ParentID)
+		if err != nil {
+			return nil, err
+		}
+
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = loadTags(m.DB, "pgx", snippets)
+	if err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// This will return a page of the unexpired snippets in the order given by
+// opts.Sort.
+func (m *PostgresSnippetModel) List(opts ListOptions) (SnippetPage, error) {
+	stmt, args, err := listQuery(opts, "now() AT TIME ZONE 'utc'", func(t time.Time) any { return t })
+	if err != nil {
+		return SnippetPage{}, err
+	}
+
+	rows, err := m.DB.Query(rebind("pgx", stmt), args...)
+	if err != nil {
+		return SnippetPage{}, err
+	}
+
+	snippets, err := scanSnippets(rows)
+	if err != nil {
+		return SnippetPage{}, err
+	}
+
+	page := newPage(opts, snippets)
+
+	err = loadTags(m.DB, "pgx", page.Snippets)
+	if err != nil {
+		return SnippetPage{}, err
+	}
+
+	return page, nil
+}
+
+// This will update the title, content and expiry of an existing snippet,
+// keeping the current expiry if expires is 0.
+func (m *PostgresSnippetModel) Update(id int, title string, content string, language string, expires int, userID int, tags []string) error {
+	stmt := `UPDATE snippets SET title = $1, content = $2, language = $3,
+	expires = CASE WHEN $4 = 0 THEN expires ELSE (now() AT TIME ZONE 'utc') + make_interval(days => $4) END
+	WHERE expires > now() AT TIME ZONE 'utc' AND id = $5`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(stmt, title, content, language, expires, id)
+	if err != nil {
+		return err
+	}
+
+	// Check that the snippet really is missing before returning ErrNoRecord,
+	// since MySQL reports 0 rows affected when the values don't change.
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		exists, err := snippetExists(tx, "pgx", id)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return ErrNoRecord
+		}
+	}
+
+	err = setTags(tx, "pgx", id, tags)
+	if err != nil {
+		return err
+	}
+
+	err = addRevision(tx, "pgx", id, userID, title, content, language)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// This will delete a specific snippet based on its id.
+func (m *PostgresSnippetModel) Delete(id int) error {
+	stmt := `DELETE FROM snippets WHERE id = $1`
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// This will delete a specific snippet if the given delete key matches.
+func (m *PostgresSnippetModel) DeleteWithKey(id int, key string) error {
+	stmt := `DELETE FROM snippets WHERE id = $1 AND delete_key_hash = $2`
+
+	result, err := m.DB.Exec(stmt, id, hashSecret(key))
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrInvalidCredentials
+	}
+
+	return nil
+}
+
+// This will permanently delete up to limit snippets which have expired, and
+// return the number deleted. PostgreSQL doesn't support LIMIT on DELETE, so
+// we select the IDs to delete in a subquery.
+func (m *PostgresSnippetModel) DeleteExpired(limit int) (int, error) {
+	stmt := `DELETE FROM snippets WHERE id IN
+	(SELECT id FROM snippets WHERE expires <= now() AT TIME ZONE 'utc' LIMIT $1)`
+
+	result, err := m.DB.Exec(stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), nil
+}
+
+// This will return a page of the unexpired snippets matching a search query,
+// using the full-text index on the title and content. The query is parsed
+// with websearch_to_tsquery(), so it supports "quoted phrases", OR and -word.
+// The results are ordered by relevance. If tag isn't empty, only snippets
+// with that tag are included.
+func (m *PostgresSnippetModel) Search(query string, tag string, page int) ([]Snippet, bool, error) {
+	stmt := `SELECT id, title, content, language, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE expires > now() AT TIME ZONE 'utc'
+	AND to_tsvector('english', title || ' ' || content) @@ websearch_to_tsquery('english', ?)`
+
+	args := []any{query}
+
+	if tag != "" {
+		stmt += tagFilter
+		args = append(args, tag)
+	}
+
+	stmt += `
+	ORDER BY ts_rank(to_tsvector('english', title || ' ' || content), websearch_to_tsquery('english', ?)) DESC, id DESC
+	LIMIT ? OFFSET ?`
+
+	args = append(args, query, SearchPageSize+1, searchOffset(page))
+
+	rows, err := m.DB.Query(rebind("pgx", stmt), args...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	snippets, err := scanSnippets(rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	snippets, more := trimPage(snippets)
+
+	err = loadTags(m.DB, "pgx", snippets)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return snippets, more, nil
+}
+
+// rebind rewrites the ? placeholders in a query into the $1, $2, ... style
+// needed by PostgreSQL when driver is "pgx". For any other driver the query is
+// returned unchanged. None of our queries contain a literal question mark, so
+// a simple replacement is safe.
+func rebind(driver string, query string) string {
+	if driver != "pgx" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// This will return the unexpired snippets which were forked from a snippet,
+// newest first.
+func (m *PostgresSnippetModel) Forks(id int) ([]Snippet, error) {
+	stmt := `SELECT id, title, content, language, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE expires > now() AT TIME ZONE 'utc' AND parent_id = $1 ORDER BY id DESC`
+
+	rows, err := m.DB.Query(stmt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	snippets, err := scanSnippets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadTags(m.DB, "pgx", snippets)
+	if err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// This will return the revisions of a snippet, newest first.
+func (m *PostgresSnippetModel) Revisions(id int) ([]Revision, error) {
+	return queryRevisions(m.DB, "pgx", id)
+}
+
+// This will make an earlier revision of a snippet current again, recording
+// it as a new revision by userID.
+func (m *PostgresSnippetModel) Restore(id int, version int, userID int) error {
+	return restoreRevision(m.DB, "pgx", id, version, userID)
+}
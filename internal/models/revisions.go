@@ -0,0 +1,180 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Define a Revision type to hold one saved version of a snippet. Versions
+// are numbered from 1 for each snippet, and the latest revision always
+// matches the snippet itself.
+type Revision struct {
+	SnippetID int
+	Version   int
+	Title     string
+	Content   string
+	Language  string
+	// UserID is the ID of the user who saved the revision, or 0 if it was
+	// saved anonymously (or the user has since been deleted).
+	UserID  int
+	Created time.Time
+}
+
+// utcNow holds the SQL expression for the current UTC time for each driver.
+var utcNow = map[string]string{
+	"mysql":  "UTC_TIMESTAMP()",
+	"sqlite": "datetime('now')",
+	"pgx":    "now() AT TIME ZONE 'utc'",
+}
+
+// forUpdate holds the clause which locks the rows read by a SELECT until the
+// end of the transaction for each driver. SQLite has no such clause, and
+// doesn't need one since it only allows one write transaction at a time.
+var forUpdate = map[string]string{
+	"mysql":  " FOR UPDATE",
+	"sqlite": "",
+	"pgx":    " FOR UPDATE",
+}
+
+// snippetExists reports whether there is an unexpired snippet with the given
+// ID. MySQL reports 0 rows affected by an UPDATE when the values don't change,
+// so this is used to tell that apart from the snippet being missing.
+func snippetExists(tx *sql.Tx, driver string, id int) (bool, error) {
+	var exists bool
+
+	stmt := `SELECT EXISTS(SELECT true FROM snippets WHERE expires > ` + utcNow[driver] + ` AND id = ?)`
+
+	err := tx.QueryRow(rebind(driver, stmt), id).Scan(&exists)
+
+	return exists, err
+}
+
+// addRevision records the title, content and language of a snippet as its
+// next revision within a transaction. Nothing is recorded if they are the
+// same as the latest revision, so that changing just the tags or expiry
+// doesn't add an empty entry to the history.
+func addRevision(tx *sql.Tx, driver string, snippetID int, userID int, title string, content string, language string) error {
+	// Lock the snippet's row, so that concurrent updates of the same snippet
+	// take turns here rather than both reading the same latest version and
+	// failing on the duplicate key when inserting the next one.
+	if lock := forUpdate[driver]; lock != "" {
+		stmt := `SELECT id FROM snippets WHERE id = ?` + lock
+
+		err := tx.QueryRow(rebind(driver, stmt), snippetID).Scan(&snippetID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNoRecord
+			}
+			return err
+		}
+	}
+
+	// The latest version is read with a locking read too, since MySQL would
+	// otherwise read it from the snapshot taken when the transaction started.
+	stmt := `SELECT version, title, content, language FROM snippet_revisions
+	WHERE snippet_id = ? ORDER BY version DESC LIMIT 1` + forUpdate[driver]
+
+	var latest Revision
+
+	err := tx.QueryRow(rebind(driver, stmt), snippetID).Scan(&latest.Version, &latest.Title, &latest.Content, &latest.Language)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// This is the first revision.
+	case err != nil:
+		return err
+	case latest.Title == title && latest.Content == content && latest.Language == language:
+		return nil
+	}
+
+	stmt = `INSERT INTO snippet_revisions (snippet_id, version, title, content, language, user_id, created)
+	VALUES (?, ?, ?, ?, ?, ?, ` + utcNow[driver] + `)`
+
+	_, err = tx.Exec(rebind(driver, stmt), snippetID, latest.Version+1, title, content, language, nullableID(userID))
+
+	return err
+}
+
+// queryRevisions returns the revisions of a snippet, newest first.
+func queryRevisions(db *sql.DB, driver string, snippetID int) ([]Revision, error) {
+	stmt := `SELECT snippet_id, version, title, content, language, COALESCE(user_id, 0), created
+	FROM snippet_revisions WHERE snippet_id = ? ORDER BY version DESC`
+
+	rows, err := db.Query(rebind(driver, stmt), snippetID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var revisions []Revision
+
+	for rows.Next() {
+		var r Revision
+
+		err = rows.Scan(&r.SnippetID, &r.Version, &r.Title, &r.Content, &r.Language, &r.UserID, &r.Created)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, r)
+	}
+
+	return revisions, rows.Err()
+}
+
+// restoreRevision makes an earlier revision of an unexpired snippet current
+// again, recording it as a new revision by userID. The snippet's expiry and
+// tags are left alone. It returns ErrNoRecord if there is no such snippet or
+// revision.
+func restoreRevision(db *sql.DB, driver string, snippetID int, version int, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT title, content, language FROM snippet_revisions
+	WHERE snippet_id = ? AND version = ?`
+
+	var r Revision
+
+	err = tx.QueryRow(rebind(driver, stmt), snippetID, version).Scan(&r.Title, &r.Content, &r.Language)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	stmt = `UPDATE snippets SET title = ?, content = ?, language = ?
+	WHERE expires > ` + utcNow[driver] + ` AND id = ?`
+
+	result, err := tx.Exec(rebind(driver, stmt), r.Title, r.Content, r.Language, snippetID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		exists, err := snippetExists(tx, driver, snippetID)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return ErrNoRecord
+		}
+	}
+
+	err = addRevision(tx, driver, snippetID, userID, r.Title, r.Content, r.Language)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,50 @@
+package models
+
+import "strings"
+
+// SearchPageSize is the number of results in each page returned by the
+// SnippetStore Search() method.
+const SearchPageSize = 10
+
+// maxSearchTerms limits the number of words from a search query used by the
+// LIKE-based searches, since each one adds a condition to the query.
+const maxSearchTerms = 10
+
+// searchTerms splits a search query into the words which must all appear in
+// the title or content of a matching snippet, for the stores which don't have
+// a full-text index.
+func searchTerms(query string) []string {
+	terms := strings.Fields(query)
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+
+	return terms
+}
+
+// likePattern returns a LIKE pattern which matches any string containing
+// term, escaping the LIKE wildcards % and _ (and the escape character \).
+func likePattern(term string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(term) + "%"
+}
+
+// searchOffset returns the OFFSET for a 1-based page number.
+func searchOffset(page int) int {
+	if page < 1 {
+		page = 1
+	}
+
+	return (page - 1) * SearchPageSize
+}
+
+// trimPage takes up to SearchPageSize+1 results and returns the first
+// SearchPageSize of them, along with whether there was another result (and
+// so another page).
+func trimPage(snippets []Snippet) ([]Snippet, bool) {
+	if len(snippets) > SearchPageSize {
+		return snippets[:SearchPageSize], true
+	}
+
+	return snippets, false
+}
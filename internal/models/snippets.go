@@ -6,13 +6,25 @@ import (
 	"time"
 )
 
-// Define a Snippet type to holld the data for an individual snippet.
+// Define a Snippet type to holld the data for an individual snippet. The
+// struct tags control how it is encoded by the JSON API.
 type Snippet struct {
-	ID      int
-	Title   string
-	Content string
-	Created time.Time
-	Expires time.Time
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	// Language is the name of the language used to highlight the content,
+	// or empty to detect it automatically.
+	Language string    `json:"language"`
+	Created  time.Time `json:"created"`
+	Expires  time.Time `json:"expires"`
+	// UserID is the ID of the user who created the snippet, or 0 if it was
+	// created anonymously.
+	UserID int `json:"user_id,omitempty"`
+	// Tags holds the snippet's tags, sorted by name.
+	Tags []string `json:"tags,omitempty"`
+	// ParentID is the ID of the snippet this one was forked from, or 0 if
+	// it isn't a fork (or the original has been deleted).
+	ParentID int `json:"parent_id,omitempty"`
 }
 
 // Define a SnippetModel type which wraps a sql.DB connection pool
@@ -20,40 +32,73 @@ type SnippetModel struct {
 	DB *sql.DB
 }
 
-// This will inset a new snippet into the database
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
+// This will inset a new snippet into the database. A userID of 0 stores the
+// snippet without an owner, and a parentID of 0 means it isn't a fork. Along
+// with the new snippet's ID it returns a random delete key, which can later be
+// used to delete the snippet without logging in. The snippet and its tags are
+// inserted in a single transaction.
+func (m *SnippetModel) Insert(title string, content string, language string, expires int, userID int, parentID int, tags []string) (int, string, error) {
 	/*
-		INSERT INTO snippets (title, content, created, expires)
-		VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(),
-		INTERVAL ? DAY))
+		INSERT INTO snippets (title, content, language, created, expires, user_id, parent_id, delete_key_hash)
+		VALUES(?, ?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(),
+		INTERVAL ? DAY), ?, ?, ?)
 	*/
-	stmt := `INSERT INTO snippets (title, content, created, expires)
-	VALUE(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+	stmt := `INSERT INTO snippets (title, content, language, created, expires, user_id, parent_id, delete_key_hash)
+	VALUE(?, ?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY), ?, ?, ?)`
 
-	result, err := m.DB.Exec(stmt, title, content, expires)
+	key, hash, err := generateSecret()
 	if err != nil {
-		return 0, err
+		return 0, "", err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, "", err
+	}
+
+	// Calling Rollback() after a successful Commit() does nothing, so it is
+	// safe to defer it straight away.
+	defer tx.Rollback()
+
+	result, err := tx.Exec(stmt, title, content, language, expires, nullableID(userID), nullableID(parentID), hash)
+	if err != nil {
+		return 0, "", err
 	}
 
 	// Use the LastInsertId() method on the result to get the ID of our
 	// newly inserted record in the snippets table.
 	id, err := result.LastInsertId()
 	if err != nil {
-		return 0, err
+		return 0, "", err
 	}
 
-	return int(id), nil
+	err = setTags(tx, "mysql", int(id), tags)
+	if err != nil {
+		return 0, "", err
+	}
+
+	err = addRevision(tx, "mysql", int(id), userID, title, content, language)
+	if err != nil {
+		return 0, "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, "", err
+	}
+
+	return int(id), key, nil
 }
 
 // This will return a specific snippet based on its id.
 func (m *SnippetModel) Get(id int) (Snippet, error) {
 
-	stmt := `SELECT id, title, content, created, expires FROM snippets
+	stmt := `SELECT id, title, content, language, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
 	WHERE expires > UTC_TIMESTAMP() AND id = ?`
 
 	var s Snippet
 
-	err := m.DB.QueryRow(stmt, id).Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	err := m.DB.QueryRow(stmt, id).Scan(&s.ID, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires, &s.UserID, &s.ParentID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return Snippet{}, ErrNoRecord
@@ -62,17 +107,34 @@ func (m *SnippetModel) Get(id int) (Snippet, error) {
 		}
 	}
 
-	return s, nil
+	snippets := []Snippet{s}
+
+	err = loadTags(m.DB, "mysql", snippets)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	return snippets[0], nil
 }
 
-// This will return the 10 most recently created snippets.
-func (m *SnippetModel) Latest() ([]Snippet, error) {
+// This will return the 10 most recently created snippets, only including
+// those with the given tag unless it is empty.
+func (m *SnippetModel) Latest(tag string) ([]Snippet, error) {
+
+	stmt := `SELECT id, title, content, language, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE expires > UTC_TIMESTAMP()`
+
+	var args []any
+
+	if tag != "" {
+		stmt += tagFilter
+		args = append(args, tag)
+	}
 
-	stmt := `SELECT id, title, content, created, expires FROM snippets 
-	WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
+	stmt += ` ORDER BY id DESC LIMIT 10`
 
 	// returns sql.Rows resultset containing the result of the query
-	rows, err := m.DB.Query(stmt)
+	rows, err := m.DB.Query(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +157,7 @@ func (m *SnippetModel) Latest() ([]Snippet, error) {
 	for rows.Next() {
 		var s Snippet
 
-		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires, &s.UserID, &s.ParentID)
 		if err != nil {
 			return nil, err
 		}
@@ -111,5 +173,266 @@ func (m *SnippetModel) Latest() ([]Snippet, error) {
 		return nil, err
 	}
 
+	err = loadTags(m.DB, "mysql", snippets)
+	if err != nil {
+		return nil, err
+	}
+
 	return snippets, nil
 }
+
+// This will return a page of the unexpired snippets in the order given by
+// opts.Sort. Rather than using OFFSET, which gets slower the further in we go
+// and skips or repeats snippets when others are added or deleted, it uses
+// "keyset" pagination: each page starts from the position of the last
+// snippet on the previous one, which the cursor records.
+func (m *SnippetModel) List(opts ListOptions) (SnippetPage, error) {
+	stmt, args, err := listQuery(opts, "UTC_TIMESTAMP()", func(t time.Time) any { return t })
+	if err != nil {
+		return SnippetPage{}, err
+	}
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return SnippetPage{}, err
+	}
+
+	snippets, err := scanSnippets(rows)
+	if err != nil {
+		return SnippetPage{}, err
+	}
+
+	page := newPage(opts, snippets)
+
+	err = loadTags(m.DB, "mysql", page.Snippets)
+	if err != nil {
+		return SnippetPage{}, err
+	}
+
+	return page, nil
+}
+
+// This will update the title, content and expiry of an existing snippet. The
+// new expiry is calculated from the current time, or left alone if expires is
+// 0. Changes to the title, content or language are recorded as a new revision
+// by userID. If there is no unexpired snippet with that id we return the
+// ErrNoRecord error.
+func (m *SnippetModel) Update(id int, title string, content string, language string, expires int, userID int, tags []string) error {
+	stmt := `UPDATE snippets SET title = ?, content = ?, language = ?,
+	expires = CASE WHEN ? = 0 THEN expires ELSE DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY) END
+	WHERE expires > UTC_TIMESTAMP() AND id = ?`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(stmt, title, content, language, expires, expires, id)
+	if err != nil {
+		return err
+	}
+
+	// Check that the snippet really is missing before returning ErrNoRecord,
+	// since MySQL reports 0 rows affected when the values don't change.
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		exists, err := snippetExists(tx, "mysql", id)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return ErrNoRecord
+		}
+	}
+
+	err = setTags(tx, "mysql", id, tags)
+	if err != nil {
+		return err
+	}
+
+	err = addRevision(tx, "mysql", id, userID, title, content, language)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// This will delete a specific snippet based on its id. If no snippet with
+// that id exists we return the ErrNoRecord error.
+func (m *SnippetModel) Delete(id int) error {
+	stmt := `DELETE FROM snippets WHERE id = ?`
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	// Use the RowsAffected() method on the result to check whether a row was
+	// actually deleted.
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// This will delete a specific snippet if the given delete key matches the one
+// generated when it was created. If the key doesn't match (or the snippet
+// has no key) we return the ErrInvalidCredentials error.
+func (m *SnippetModel) DeleteWithKey(id int, key string) error {
+	stmt := `DELETE FROM snippets WHERE id = ? AND delete_key_hash = ?`
+
+	result, err := m.DB.Exec(stmt, id, hashSecret(key))
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrInvalidCredentials
+	}
+
+	return nil
+}
+
+// This will permanently delete up to limit snippets which have expired, and
+// return the number deleted.
+func (m *SnippetModel) DeleteExpired(limit int) (int, error) {
+	stmt := `DELETE FROM snippets WHERE expires <= UTC_TIMESTAMP() LIMIT ?`
+
+	result, err := m.DB.Exec(stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), nil
+}
+
+// This will return a page of the unexpired snippets matching a search query,
+// using the full-text index on the title and content columns. The results are
+// ordered by relevance, and the second return value reports whether there are
+// more pages. If tag isn't empty, only snippets with that tag are included.
+func (m *SnippetModel) Search(query string, tag string, page int) ([]Snippet, bool, error) {
+	stmt := `SELECT id, title, content, language, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE expires > UTC_TIMESTAMP() AND MATCH(title, content) AGAINST(? IN NATURAL LANGUAGE MODE)`
+
+	args := []any{query}
+
+	if tag != "" {
+		stmt += tagFilter
+		args = append(args, tag)
+	}
+
+	stmt += ` ORDER BY MATCH(title, content) AGAINST(? IN NATURAL LANGUAGE MODE) DESC, id DESC
+	LIMIT ? OFFSET ?`
+
+	// We ask for one more row than fits on a page, to find out whether there
+	// is a next page.
+	args = append(args, query, SearchPageSize+1, searchOffset(page))
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	snippets, err := scanSnippets(rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	snippets, more := trimPage(snippets)
+
+	err = loadTags(m.DB, "mysql", snippets)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return snippets, more, nil
+}
+
+// scanSnippets reads every row from a result set with the columns id, title,
+// content, created, expires and user_id, then closes it.
+func scanSnippets(rows *sql.Rows) ([]Snippet, error) {
+	defer rows.Close()
+
+	var snippets []Snippet
+
+	for rows.Next() {
+		var s Snippet
+
+		err := rows.Scan(&s.ID, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires, &s.UserID, &s.ParentID)
+		if err != nil {
+			return nil, err
+		}
+
+		snippets = append(snippets, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// nullableID converts an ID where 0 means "none" into a value which is stored
+// as NULL in the database.
+func nullableID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id > 0}
+}
+
+// This will return the unexpired snippets which were forked from a snippet,
+// newest first.
+func (m *SnippetModel) Forks(id int) ([]Snippet, error) {
+	stmt := `SELECT id, title, content, language, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE expires > UTC_TIMESTAMP() AND parent_id = ? ORDER BY id DESC`
+
+	rows, err := m.DB.Query(stmt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	snippets, err := scanSnippets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadTags(m.DB, "mysql", snippets)
+	if err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// This will return the revisions of a snippet, newest first.
+func (m *SnippetModel) Revisions(id int) ([]Revision, error) {
+	return queryRevisions(m.DB, "mysql", id)
+}
+
+// This will make an earlier revision of a snippet current again, recording
+// it as a new revision by userID.
+func (m *SnippetModel) Restore(id int, version int, userID int) error {
+	return restoreRevision(m.DB, "mysql", id, version, userID)
+}
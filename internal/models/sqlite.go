@@ -0,0 +1,352 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Define a SQLiteSnippetModel type which wraps a sql.DB connection pool for a
+// SQLite database. It behaves exactly like the MySQL SnippetModel, but uses
+// SQLite's datetime() function in place of UTC_TIMESTAMP() and DATE_ADD().
+// Both return UTC times with whole-second precision.
+type SQLiteSnippetModel struct {
+	DB *sql.DB
+}
+
+// This will insert a new snippet into the database.
+func (m *SQLiteSnippetModel) Insert(title string, content string, language string, expires int, userID int, parentID int, tags []string) (int, string, error) {
+	stmt := `INSERT INTO snippets (title, content, language, created, expires, user_id, parent_id, delete_key_hash)
+	VALUES(?, ?, ?, datetime('now'), datetime('now', '+' || ? || ' days'), ?, ?, ?)`
+
+	key, hash, err := generateSecret()
+	if err != nil {
+		return 0, "", err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(stmt, title, content, language, expires, nullableID(userID), nullableID(parentID), hash)
+	if err != nil {
+		return 0, "", err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, "", err
+	}
+
+	err = setTags(tx, "sqlite", int(id), tags)
+	if err != nil {
+		return 0, "", err
+	}
+
+	err = addRevision(tx, "sqlite", int(id), userID, title, content, language)
+	if err != nil {
+		return 0, "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, "", err
+	}
+
+	return int(id), key, nil
+}
+
+// This will return a specific snippet based on its id.
+func (m *SQLiteSnippetModel) Get(id int) (Snippet, error) {
+	stmt := `SELECT id, title, content, language, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE expires > datetime('now') AND id = ?`
+
+	var s Snippet
+
+	err := m.DB.QueryRow(stmt, id).Scan(&s.ID, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires, &s.UserID, &s.ParentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Snippet{}, ErrNoRecord
+		} else {
+			return Snippet{}, err
+		}
+	}
+
+	snippets := []Snippet{s}
+
+	err = loadTags(m.DB, "sqlite", snippets)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	return snippets[0], nil
+}
+
+// This will return the 10 most recently created snippets, only including
+// those with the given tag unless it is empty.
+func (m *SQLiteSnippetModel) Latest(tag string) ([]Snippet, error) {
+	stmt := `SELECT id, title, content, language, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE expires > datetime('now')`
+
+	var args []any
+
+	if tag != "" {
+		stmt += tagFilter
+		args = append(args, tag)
+	}
+
+	stmt += ` ORDER BY id DESC LIMIT 10`
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var snippets []Snippet
+
+	for rows.Next() {
+		var s Snippet
+
+		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires, &s.UserID, &s.ParentID)
+		if err != nil {
+			return nil, err
+		}
+
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = loadTags(m.DB, "sqlite", snippets)
+	if err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// This will return a page of the unexpired snippets in the order given by
+// opts.Sort. SQLite stores the times as text, so the expiry time from a
+// cursor is formatted the same way as datetime() before being compared.
+func (m *SQLiteSnippetModel) List(opts ListOptions) (SnippetPage, error) {
+	stmt, args, err := listQuery(opts, "datetime('now')", func(t time.Time) any {
+		return t.UTC().Format(time.DateTime)
+	})
+	if err != nil {
+		return SnippetPage{}, err
+	}
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return SnippetPage{}, err
+	}
+
+	snippets, err := scanSnippets(rows)
+	if err != nil {
+		return SnippetPage{}, err
+	}
+
+	page := newPage(opts, snippets)
+
+	err = loadTags(m.DB, "sqlite", page.Snippets)
+	if err != nil {
+		return SnippetPage{}, err
+	}
+
+	return page, nil
+}
+
+// This will update the title, content and expiry of an existing snippet,
+// keeping the current expiry if expires is 0.
+func (m *SQLiteSnippetModel) Update(id int, title string, content string, language string, expires int, userID int, tags []string) error {
+	stmt := `UPDATE snippets SET title = ?, content = ?, language = ?,
+	expires = CASE WHEN ? = 0 THEN expires ELSE datetime('now', '+' || ? || ' days') END
+	WHERE expires > datetime('now') AND id = ?`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(stmt, title, content, language, expires, expires, id)
+	if err != nil {
+		return err
+	}
+
+	// Check that the snippet really is missing before returning ErrNoRecord,
+	// since MySQL reports 0 rows affected when the values don't change.
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		exists, err := snippetExists(tx, "sqlite", id)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return ErrNoRecord
+		}
+	}
+
+	err = setTags(tx, "sqlite", id, tags)
+	if err != nil {
+		return err
+	}
+
+	err = addRevision(tx, "sqlite", id, userID, title, content, language)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// This will delete a specific snippet based on its id.
+func (m *SQLiteSnippetModel) Delete(id int) error {
+	stmt := `DELETE FROM snippets WHERE id = ?`
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// This will delete a specific snippet if the given delete key matches.
+func (m *SQLiteSnippetModel) DeleteWithKey(id int, key string) error {
+	stmt := `DELETE FROM snippets WHERE id = ? AND delete_key_hash = ?`
+
+	result, err := m.DB.Exec(stmt, id, hashSecret(key))
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrInvalidCredentials
+	}
+
+	return nil
+}
+
+// This will permanently delete up to limit snippets which have expired, and
+// return the number deleted. SQLite doesn't support LIMIT on DELETE by
+// default, so we select the IDs to delete in a subquery.
+func (m *SQLiteSnippetModel) DeleteExpired(limit int) (int, error) {
+	stmt := `DELETE FROM snippets WHERE id IN
+	(SELECT id FROM snippets WHERE expires <= datetime('now') LIMIT ?)`
+
+	result, err := m.DB.Exec(stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), nil
+}
+
+// This will return a page of the unexpired snippets whose title or content
+// contains every word of the search query (ignoring case for ASCII letters),
+// newest first. SQLite has no full-text index here, so we fall back to LIKE.
+// If tag isn't empty, only snippets with that tag are included.
+func (m *SQLiteSnippetModel) Search(query string, tag string, page int) ([]Snippet, bool, error) {
+	stmt := `SELECT id, title, content, language, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE expires > datetime('now')`
+
+	var args []any
+
+	if tag != "" {
+		stmt += tagFilter
+		args = append(args, tag)
+	}
+
+	for _, term := range searchTerms(query) {
+		stmt += ` AND (title LIKE ? ESCAPE '\' OR content LIKE ? ESCAPE '\')`
+		args = append(args, likePattern(term), likePattern(term))
+	}
+
+	stmt += ` ORDER BY id DESC LIMIT ? OFFSET ?`
+	args = append(args, SearchPageSize+1, searchOffset(page))
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	snippets, err := scanSnippets(rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	snippets, more := trimPage(snippets)
+
+	err = loadTags(m.DB, "sqlite", snippets)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return snippets, more, nil
+}
+
+// This will return the unexpired snippets which were forked from a snippet,
+// newest first.
+func (m *SQLiteSnippetModel) Forks(id int) ([]Snippet, error) {
+	stmt := `SELECT id, title, content, language, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE expires > datetime('now') AND parent_id = ? ORDER BY id DESC`
+
+	rows, err := m.DB.Query(stmt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	snippets, err := scanSnippets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadTags(m.DB, "sqlite", snippets)
+	if err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// This will return the revisions of a snippet, newest first.
+func (m *SQLiteSnippetModel) Revisions(id int) ([]Revision, error) {
+	return queryRevisions(m.DB, "sqlite", id)
+}
+
+// This will make an earlier revision of a snippet current again, recording
+// it as a new revision by userID.
+func (m *SQLiteSnippetModel) Restore(id int, version int, userID int) error {
+	return restoreRevision(m.DB, "sqlite", id, version, userID)
+}
@@ -0,0 +1,54 @@
+package models
+
+// SnippetStore is the interface implemented by each of the snippet storage
+// backends. All implementations must behave identically: expired snippets are
+// never returned by Get, Latest, List or Search, and Update and Restore return
+// ErrNoRecord for them, but they can still be deleted until they are removed
+// from the store. Insert, Update and Restore record each new version of a
+// snippet's title, content and language as a Revision.
+type SnippetStore interface {
+	Insert(title string, content string, language string, expires int, userID int, parentID int, tags []string) (int, string, error)
+	Get(id int) (Snippet, error)
+	Latest(tag string) ([]Snippet, error)
+	List(opts ListOptions) (SnippetPage, error)
+	Update(id int, title string, content string, language string, expires int, userID int, tags []string) error
+	Delete(id int) error
+	DeleteWithKey(id int, key string) error
+	DeleteExpired(limit int) (int, error)
+	Search(query string, tag string, page int) ([]Snippet, bool, error)
+	Forks(id int) ([]Snippet, error)
+	Revisions(id int) ([]Revision, error)
+	Restore(id int, version int, userID int) error
+}
+
+// Check at compile time that each backend satisfies the SnippetStore
+// interface.
+var (
+	_ SnippetStore = (*SnippetModel)(nil)
+	_ SnippetStore = (*SQLiteSnippetModel)(nil)
+	_ SnippetStore = (*PostgresSnippetModel)(nil)
+	_ SnippetStore = (*MemorySnippetModel)(nil)
+)
+
+// UserStore is the interface implemented by UserModel and MemoryUserModel.
+type UserStore interface {
+	Insert(name, email, password string) error
+	Authenticate(email, password string) (int, error)
+	Exists(id int) (bool, error)
+	Get(id int) (User, error)
+}
+
+// TokenStore is the interface implemented by TokenModel and MemoryTokenModel.
+type TokenStore interface {
+	Insert(userID int, name string, scope string) (string, error)
+	List(userID int) ([]Token, error)
+	Revoke(id int, userID int) error
+	Authenticate(token string) (Token, error)
+}
+
+var (
+	_ UserStore  = (*UserModel)(nil)
+	_ UserStore  = (*MemoryUserModel)(nil)
+	_ TokenStore = (*TokenModel)(nil)
+	_ TokenStore = (*MemoryTokenModel)(nil)
+)
@@ -0,0 +1,886 @@
+package models_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Galbeyte1/snippetbox/internal/migrations"
+	"github.com/Galbeyte1/snippetbox/internal/models"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// newStoreFunc returns a new, empty SnippetStore along with the ID of a user
+// who can own snippets in it.
+type newStoreFunc func(t *testing.T) (models.SnippetStore, int)
+
+func TestMemorySnippetModel(t *testing.T) {
+	testSnippetStore(t, func(t *testing.T) (models.SnippetStore, int) {
+		return models.NewMemorySnippetModel(), 1
+	})
+}
+
+func TestSQLiteSnippetModel(t *testing.T) {
+	testSnippetStore(t, func(t *testing.T) (models.SnippetStore, int) {
+		dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+		db := newTestDB(t, "sqlite", dsn)
+
+		return &models.SQLiteSnippetModel{DB: db}, newTestUser(t, db, "sqlite")
+	})
+}
+
+// The MySQL and PostgreSQL tests need a database to run against, which is
+// given by the SNIPPETBOX_TEST_MYSQL_DSN (with parseTime=true) or
+// SNIPPETBOX_TEST_POSTGRES_DSN environment variable. They are skipped if it
+// isn't set. Use a database just for testing, since every snippet in it is
+// deleted.
+func TestSnippetModel(t *testing.T) {
+	dsn := os.Getenv("SNIPPETBOX_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("SNIPPETBOX_TEST_MYSQL_DSN not set")
+	}
+
+	testSnippetStore(t, func(t *testing.T) (models.SnippetStore, int) {
+		db := newTestDB(t, "mysql", dsn)
+
+		return &models.SnippetModel{DB: db}, newTestUser(t, db, "mysql")
+	})
+}
+
+func TestPostgresSnippetModel(t *testing.T) {
+	dsn := os.Getenv("SNIPPETBOX_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("SNIPPETBOX_TEST_POSTGRES_DSN not set")
+	}
+
+	testSnippetStore(t, func(t *testing.T) (models.SnippetStore, int) {
+		db := newTestDB(t, "pgx", dsn)
+
+		return &models.PostgresSnippetModel{DB: db}, newTestUser(t, db, "pgx")
+	})
+}
+
+// newTestDB opens a database, applies the migrations and deletes any
+// snippets left over from earlier tests. The database is closed when the test
+// finishes.
+func newTestDB(t *testing.T, driver string, dsn string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.New(db, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = migrator.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec("DELETE FROM snippets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// newTestUser creates a user to own snippets, returning their ID.
+func newTestUser(t *testing.T, db *sql.DB, driver string) int {
+	t.Helper()
+
+	users := &models.UserModel{DB: db, Driver: driver}
+	email := fmt.Sprintf("store-test-%d@example.com", time.Now().UnixNano())
+
+	err := users.Insert("Store Test", email, "pa55word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := users.Authenticate(email, "pa55word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+// testSnippetStore runs the checks which every SnippetStore implementation
+// must pass. Each subtest gets a new, empty store.
+func testSnippetStore(t *testing.T, newStore newStoreFunc) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store models.SnippetStore, userID int)
+	}{
+		{"InsertAndGet", testInsertAndGet},
+		{"Expired", testExpired},
+		{"Latest", testLatest},
+		{"List", testList},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"DeleteWithKey", testDeleteWithKey},
+		{"DeleteExpired", testDeleteExpired},
+		{"Search", testSearch},
+		{"TagsCopied", testTagsCopied},
+		{"Forks", testForks},
+		{"Revisions", testRevisions},
+		{"Restore", testRestore},
+		{"ConcurrentUpdates", testConcurrentUpdates},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, userID := newStore(t)
+			tt.test(t, store, userID)
+		})
+	}
+}
+
+func testInsertAndGet(t *testing.T, store models.SnippetStore, userID int) {
+	id, key, err := store.Insert("O snail", "Climb Mount Fuji", "text", 7, userID, 0, []string{"haiku", "poems"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id < 1 || key == "" {
+		t.Fatalf("got ID %d and key %q; want a positive ID and a key", id, key)
+	}
+
+	s, err := store.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.ID != id || s.Title != "O snail" || s.Content != "Climb Mount Fuji" || s.Language != "text" || s.UserID != userID {
+		t.Errorf("got %+v; want the inserted snippet", s)
+	}
+
+	if !slices.Equal(s.Tags, []string{"haiku", "poems"}) {
+		t.Errorf("got tags %q; want [haiku poems]", s.Tags)
+	}
+
+	if got := s.Expires.Sub(s.Created); got != 7*24*time.Hour {
+		t.Errorf("got expiry %s after creation; want 168h", got)
+	}
+
+	anonymousID, _, err := store.Insert("Anonymous", "content", "", 1, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s = mustGet(t, store, anonymousID)
+	if s.UserID != 0 || len(s.Tags) != 0 {
+		t.Errorf("got user %d and tags %q; want 0 and none", s.UserID, s.Tags)
+	}
+
+	_, err = store.Get(anonymousID + 100)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("got error %v for a missing snippet; want ErrNoRecord", err)
+	}
+}
+
+func testExpired(t *testing.T, store models.SnippetStore, userID int) {
+	id := mustInsertExpired(t, store, "Expired")
+
+	_, err := store.Get(id)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("Get: got error %v; want ErrNoRecord", err)
+	}
+
+	latest, err := store.Latest("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(latest) != 0 {
+		t.Errorf("Latest: got %d snippets; want none", len(latest))
+	}
+
+	err = store.Update(id, "Updated", "content", "", 7, userID, nil)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("Update: got error %v; want ErrNoRecord", err)
+	}
+
+	err = store.Restore(id, 1, userID)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("Restore: got error %v; want ErrNoRecord", err)
+	}
+
+	// Expired snippets can still be deleted until they are removed.
+	err = store.Delete(id)
+	if err != nil {
+		t.Errorf("Delete: got error %v; want nil", err)
+	}
+}
+
+func testLatest(t *testing.T, store models.SnippetStore, userID int) {
+	var ids []int
+
+	for i := range 12 {
+		var tags []string
+		if i%3 == 0 {
+			tags = []string{"third"}
+		}
+
+		ids = append(ids, mustInsert(t, store, fmt.Sprintf("Snippet %d", i), 7, tags))
+	}
+
+	mustInsertExpired(t, store, "Expired")
+
+	latest, err := store.Latest("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The 10 newest, newest first.
+	want := slices.Clone(ids[2:])
+	slices.Reverse(want)
+
+	if got := snippetIDs(latest); !slices.Equal(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+
+	latest, err = store.Latest("third")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = []int{ids[9], ids[6], ids[3], ids[0]}
+
+	if got := snippetIDs(latest); !slices.Equal(got, want) {
+		t.Errorf("got %v with tag; want %v", got, want)
+	}
+}
+
+func testList(t *testing.T, store models.SnippetStore, userID int) {
+	const n = 2*models.ListPageSize + 5
+
+	var ids []int
+
+	for i := range n {
+		// Use expiry periods and titles which aren't in the same order as
+		// the IDs, with some duplicates to check the tie-breaking.
+		expires := []int{365, 1, 7}[i%3]
+		title := fmt.Sprintf("Title %02d", (i*7)%(n/2))
+
+		var tags []string
+		if i%2 == 0 {
+			tags = []string{"even"}
+		}
+
+		ids = append(ids, mustInsert(t, store, title, expires, tags))
+	}
+
+	mustInsertExpired(t, store, "Expired")
+
+	snippets := make(map[int]models.Snippet)
+	for _, id := range ids {
+		snippets[id] = mustGet(t, store, id)
+	}
+
+	orders := []struct {
+		sort    string
+		tag     string
+		compare func(a, b models.Snippet) int
+	}{
+		{models.SortNewest, "", func(a, b models.Snippet) int { return b.ID - a.ID }},
+		{"", "even", func(a, b models.Snippet) int { return b.ID - a.ID }},
+		{models.SortExpiring, "", func(a, b models.Snippet) int {
+			if c := a.Expires.Compare(b.Expires); c != 0 {
+				return c
+			}
+			return a.ID - b.ID
+		}},
+		{models.SortTitle, "even", func(a, b models.Snippet) int {
+			if a.Title != b.Title {
+				if a.Title < b.Title {
+					return -1
+				}
+				return 1
+			}
+			return a.ID - b.ID
+		}},
+	}
+
+	for _, order := range orders {
+		var want []models.Snippet
+		for _, s := range snippets {
+			if order.tag == "" || slices.Contains(s.Tags, order.tag) {
+				want = append(want, s)
+			}
+		}
+		slices.SortFunc(want, order.compare)
+		wantIDs := snippetIDs(want)
+
+		// Page forwards through the whole listing with the Next cursors.
+		var pages []models.SnippetPage
+
+		opts := models.ListOptions{Sort: order.sort, Tag: order.tag}
+		for {
+			page, err := store.List(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(page.Snippets) > models.ListPageSize {
+				t.Fatalf("sort %q: got %d snippets on a page; want at most %d", order.sort, len(page.Snippets), models.ListPageSize)
+			}
+
+			if (len(pages) == 0) != (page.Prev == "") {
+				t.Errorf("sort %q page %d: got Prev %q", order.sort, len(pages)+1, page.Prev)
+			}
+
+			pages = append(pages, page)
+
+			if page.Next == "" || len(pages) > n {
+				break
+			}
+
+			opts.After, opts.Before = page.Next, ""
+		}
+
+		var got []int
+		for _, page := range pages {
+			got = append(got, snippetIDs(page.Snippets)...)
+		}
+
+		if !slices.Equal(got, wantIDs) {
+			t.Errorf("sort %q tag %q: got %v; want %v", order.sort, order.tag, got, wantIDs)
+			continue
+		}
+
+		// Then page backwards with the Prev cursors, which should give
+		// exactly the same pages.
+		for i := len(pages) - 1; i > 0; i-- {
+			opts.After, opts.Before = "", pages[i].Prev
+
+			page, err := store.List(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := snippetIDs(page.Snippets), snippetIDs(pages[i-1].Snippets); !slices.Equal(got, want) {
+				t.Errorf("sort %q: got %v going back to page %d; want %v", order.sort, got, i, want)
+			}
+
+			if page.Next == "" {
+				t.Errorf("sort %q: got no Next cursor going back to page %d", order.sort, i)
+			}
+		}
+	}
+
+	_, err := store.List(models.ListOptions{After: "not a cursor"})
+	if !errors.Is(err, models.ErrInvalidCursor) {
+		t.Errorf("got error %v for an invalid cursor; want ErrInvalidCursor", err)
+	}
+
+	_, err = store.List(models.ListOptions{Sort: "random"})
+	if err == nil {
+		t.Error("got no error for an unknown sort order")
+	}
+}
+
+func testUpdate(t *testing.T, store models.SnippetStore, userID int) {
+	id := mustInsert(t, store, "Original", 1, []string{"old"})
+	original := mustGet(t, store, id)
+
+	err := store.Update(id, "Updated", "new content", "go", 7, userID, []string{"new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := mustGet(t, store, id)
+
+	if s.Title != "Updated" || s.Content != "new content" || s.Language != "go" || !slices.Equal(s.Tags, []string{"new"}) {
+		t.Errorf("got %+v; want the updated snippet", s)
+	}
+
+	if !s.Created.Equal(original.Created) {
+		t.Errorf("got created time %s; want it unchanged", s.Created)
+	}
+
+	if s.Expires.Sub(original.Expires) < 6*24*time.Hour {
+		t.Errorf("got expiry %s; want about a week from now", s.Expires)
+	}
+
+	// An expiry of 0 keeps the current one. Updating with the same title,
+	// content and language must still succeed and change the tags, even
+	// though MySQL reports that no rows changed.
+	err = store.Update(id, "Updated", "new content", "go", 0, userID, []string{"newer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept := mustGet(t, store, id)
+
+	if !kept.Expires.Equal(s.Expires) {
+		t.Errorf("got expiry %s; want it kept at %s", kept.Expires, s.Expires)
+	}
+
+	if !slices.Equal(kept.Tags, []string{"newer"}) {
+		t.Errorf("got tags %q; want [newer]", kept.Tags)
+	}
+
+	err = store.Update(id+100, "Missing", "content", "", 7, userID, nil)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("got error %v for a missing snippet; want ErrNoRecord", err)
+	}
+}
+
+func testDelete(t *testing.T, store models.SnippetStore, userID int) {
+	id := mustInsert(t, store, "Doomed", 7, []string{"gone"})
+
+	err := store.Delete(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Get(id)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("got error %v getting a deleted snippet; want ErrNoRecord", err)
+	}
+
+	err = store.Delete(id)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("got error %v deleting twice; want ErrNoRecord", err)
+	}
+}
+
+func testDeleteWithKey(t *testing.T, store models.SnippetStore, userID int) {
+	id, key, err := store.Insert("Keyed", "content", "", 7, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.DeleteWithKey(id, "wrong"+key)
+	if !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Errorf("got error %v for the wrong key; want ErrInvalidCredentials", err)
+	}
+
+	mustGet(t, store, id)
+
+	err = store.DeleteWithKey(id, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Get(id)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("got error %v getting a deleted snippet; want ErrNoRecord", err)
+	}
+
+	err = store.DeleteWithKey(id, key)
+	if !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Errorf("got error %v deleting twice; want ErrInvalidCredentials", err)
+	}
+}
+
+func testDeleteExpired(t *testing.T, store models.SnippetStore, userID int) {
+	live := mustInsert(t, store, "Live", 7, nil)
+
+	for i := range 3 {
+		mustInsertExpired(t, store, fmt.Sprintf("Expired %d", i))
+	}
+
+	for _, want := range []int{2, 1, 0} {
+		n, err := store.DeleteExpired(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != want {
+			t.Errorf("got %d deleted; want %d", n, want)
+		}
+	}
+
+	mustGet(t, store, live)
+}
+
+func testSearch(t *testing.T, store models.SnippetStore, userID int) {
+	inTitle := mustInsertContent(t, store, "Zebra crossing", "Look both ways", nil)
+	inContent := mustInsertContent(t, store, "Animals", "The zebra has stripes", []string{"animals"})
+	mustInsertContent(t, store, "Horses", "Horses have manes", []string{"animals"})
+
+	mustInsertExpired(t, store, "Extinct zebra")
+
+	results, more, err := store.Search("zebra", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := snippetIDs(results)
+	slices.Sort(got)
+
+	if !slices.Equal(got, []int{inTitle, inContent}) || more {
+		t.Errorf("got %v and more %t; want %v and false", got, more, []int{inTitle, inContent})
+	}
+
+	results, _, err = store.Search("zebra", "animals", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := snippetIDs(results); !slices.Equal(got, []int{inContent}) {
+		t.Errorf("got %v with tag; want %v", got, []int{inContent})
+	}
+
+	// Fill two pages of results for another word.
+	for i := range models.SearchPageSize + 2 {
+		mustInsertContent(t, store, fmt.Sprintf("Giraffe %d", i), "A giraffe has a long neck", nil)
+	}
+
+	results, more, err = store.Search("giraffe", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != models.SearchPageSize || !more {
+		t.Errorf("got %d results and more %t on page 1; want %d and true", len(results), more, models.SearchPageSize)
+	}
+
+	results, more, err = store.Search("giraffe", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || more {
+		t.Errorf("got %d results and more %t on page 2; want 2 and false", len(results), more)
+	}
+}
+
+// testTagsCopied checks that changing the tags of a returned snippet doesn't
+// change the stored ones.
+func testTagsCopied(t *testing.T, store models.SnippetStore, userID int) {
+	id := mustInsertContent(t, store, "Tagged", "content", []string{"haiku"})
+
+	_, _, err := store.Insert("Tagged fork", "content", "", 7, 0, id, []string{"haiku"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func() ([]models.Snippet, error) {
+		s, err := store.Get(id)
+		return []models.Snippet{s}, err
+	}
+
+	latest := func() ([]models.Snippet, error) {
+		return store.Latest("haiku")
+	}
+
+	list := func() ([]models.Snippet, error) {
+		page, err := store.List(models.ListOptions{})
+		return page.Snippets, err
+	}
+
+	search := func() ([]models.Snippet, error) {
+		snippets, _, err := store.Search("tagged", "", 1)
+		return snippets, err
+	}
+
+	forks := func() ([]models.Snippet, error) {
+		return store.Forks(id)
+	}
+
+	for _, fetch := range []func() ([]models.Snippet, error){get, latest, list, search, forks} {
+		snippets, err := fetch()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, s := range snippets {
+			if len(s.Tags) > 0 {
+				s.Tags[0] = "changed"
+			}
+		}
+	}
+
+	for _, fetch := range []func() ([]models.Snippet, error){get, latest, list, search, forks} {
+		snippets, err := fetch()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, s := range snippets {
+			if !slices.Equal(s.Tags, []string{"haiku"}) {
+				t.Errorf("got tags %q for snippet %d; want [haiku]", s.Tags, s.ID)
+			}
+		}
+	}
+}
+
+func testForks(t *testing.T, store models.SnippetStore, userID int) {
+	parent := mustInsert(t, store, "Parent", 7, nil)
+
+	first, _, err := store.Insert("First fork", "content", "", 7, userID, parent, []string{"haiku"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, _, err := store.Insert("Second fork", "content", "", 7, 0, parent, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = store.Insert("Expired fork", "content", "", 0, 0, parent, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := mustGet(t, store, first); s.ParentID != parent {
+		t.Errorf("got parent %d; want %d", s.ParentID, parent)
+	}
+
+	forks, err := store.Forks(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := snippetIDs(forks); !slices.Equal(got, []int{second, first}) {
+		t.Fatalf("got forks %v; want %v", got, []int{second, first})
+	}
+
+	if !slices.Equal(forks[1].Tags, []string{"haiku"}) || len(forks[0].Tags) != 0 {
+		t.Errorf("got tags %q and %q; want [haiku] and none", forks[1].Tags, forks[0].Tags)
+	}
+
+	// Deleting the parent keeps the forks, but unlinks them.
+	err = store.Delete(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := mustGet(t, store, first); s.ParentID != 0 {
+		t.Errorf("got parent %d after deleting it; want 0", s.ParentID)
+	}
+
+	forks, err = store.Forks(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(forks) != 0 {
+		t.Errorf("got %d forks of a deleted snippet; want none", len(forks))
+	}
+}
+
+func testRevisions(t *testing.T, store models.SnippetStore, userID int) {
+	id, _, err := store.Insert("Version 1", "one", "text", 7, userID, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Update(id, "Version 2", "two", "go", 7, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing only the tags or expiry doesn't add a revision.
+	err = store.Update(id, "Version 2", "two", "go", 1, userID, []string{"tagged"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := store.Revisions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(revisions) != 2 {
+		t.Fatalf("got %d revisions; want 2", len(revisions))
+	}
+
+	want := []models.Revision{
+		{SnippetID: id, Version: 2, Title: "Version 2", Content: "two", Language: "go", UserID: 0},
+		{SnippetID: id, Version: 1, Title: "Version 1", Content: "one", Language: "text", UserID: userID},
+	}
+
+	for i, r := range revisions {
+		if r.Created.IsZero() {
+			t.Errorf("revision %d has no created time", r.Version)
+		}
+
+		r.Created = time.Time{}
+		if r != want[i] {
+			t.Errorf("got revision %+v; want %+v", r, want[i])
+		}
+	}
+
+	revisions, err = store.Revisions(id + 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(revisions) != 0 {
+		t.Errorf("got %d revisions of a missing snippet; want none", len(revisions))
+	}
+}
+
+func testRestore(t *testing.T, store models.SnippetStore, userID int) {
+	id, _, err := store.Insert("Version 1", "one", "text", 7, 0, 0, []string{"kept"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Update(id, "Version 2", "two", "go", 7, 0, []string{"kept"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := mustGet(t, store, id)
+
+	err = store.Restore(id, 1, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := mustGet(t, store, id)
+
+	if s.Title != "Version 1" || s.Content != "one" || s.Language != "text" {
+		t.Errorf("got %+v; want version 1", s)
+	}
+
+	if !s.Expires.Equal(before.Expires) || !slices.Equal(s.Tags, []string{"kept"}) {
+		t.Errorf("got expiry %s and tags %q; want them unchanged", s.Expires, s.Tags)
+	}
+
+	revisions, err := store.Revisions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(revisions) != 3 || revisions[0].Version != 3 || revisions[0].Content != "one" || revisions[0].UserID != userID {
+		t.Errorf("got revisions %+v; want a third revision matching the first", revisions)
+	}
+
+	// Restoring the current version changes nothing, but isn't an error.
+	err = store.Restore(id, 3, userID)
+	if err != nil {
+		t.Errorf("got error %v restoring the current version; want nil", err)
+	}
+
+	revisions, err = store.Revisions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(revisions) != 3 {
+		t.Errorf("got %d revisions after restoring the current version; want 3", len(revisions))
+	}
+
+	err = store.Restore(id, 99, userID)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("got error %v for a missing version; want ErrNoRecord", err)
+	}
+
+	err = store.Restore(id+100, 1, userID)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("got error %v for a missing snippet; want ErrNoRecord", err)
+	}
+}
+
+// testConcurrentUpdates checks that updating a snippet from several goroutines
+// at once gives each update its own revision.
+func testConcurrentUpdates(t *testing.T, store models.SnippetStore, userID int) {
+	id := mustInsert(t, store, "Original", 7, nil)
+
+	const updates = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, updates)
+
+	for i := range updates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- store.Update(id, "Updated", "content "+strconv.Itoa(i), "", 7, userID, nil)
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("got error %v; want nil", err)
+		}
+	}
+
+	revisions, err := store.Revisions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(revisions) != updates+1 {
+		t.Fatalf("got %d revisions; want %d", len(revisions), updates+1)
+	}
+
+	for i, r := range revisions {
+		if want := updates + 1 - i; r.Version != want {
+			t.Errorf("got version %d; want %d", r.Version, want)
+		}
+	}
+}
+
+func mustInsert(t *testing.T, store models.SnippetStore, title string, expires int, tags []string) int {
+	t.Helper()
+
+	id, _, err := store.Insert(title, "content", "", expires, 0, 0, tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func mustInsertContent(t *testing.T, store models.SnippetStore, title string, content string, tags []string) int {
+	t.Helper()
+
+	id, _, err := store.Insert(title, content, "", 7, 0, 0, tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+// mustInsertExpired inserts a snippet which expires as soon as it is created.
+func mustInsertExpired(t *testing.T, store models.SnippetStore, title string) int {
+	t.Helper()
+
+	return mustInsert(t, store, title, 0, nil)
+}
+
+func mustGet(t *testing.T, store models.SnippetStore, id int) models.Snippet {
+	t.Helper()
+
+	s, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("getting snippet %d: %v", id, err)
+	}
+
+	return s
+}
+
+func snippetIDs(snippets []models.Snippet) []int {
+	ids := make([]int, len(snippets))
+	for i, s := range snippets {
+		ids[i] = s.ID
+	}
+
+	return ids
+}
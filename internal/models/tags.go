@@ -0,0 +1,89 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// tagFilter is the condition added to a query on the snippets table to only
+// match snippets with a given tag, using a ? placeholder for the tag name.
+const tagFilter = ` AND id IN (SELECT st.snippet_id FROM snippet_tags st
+	JOIN tags t ON t.id = st.tag_id WHERE t.name = ?)`
+
+// insertTagStmts holds the statement for each driver which adds a tag to the
+// tags table, unless it is already there.
+var insertTagStmts = map[string]string{
+	"mysql":  `INSERT IGNORE INTO tags (name) VALUES (?)`,
+	"sqlite": `INSERT OR IGNORE INTO tags (name) VALUES (?)`,
+	"pgx":    `INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`,
+}
+
+// setTags replaces the tags of a snippet within a transaction, creating any
+// tags which don't exist yet.
+func setTags(tx *sql.Tx, driver string, snippetID int, tags []string) error {
+	_, err := tx.Exec(rebind(driver, `DELETE FROM snippet_tags WHERE snippet_id = ?`), snippetID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		_, err = tx.Exec(insertTagStmts[driver], tag)
+		if err != nil {
+			return err
+		}
+
+		stmt := `INSERT INTO snippet_tags (snippet_id, tag_id) SELECT ?, id FROM tags WHERE name = ?`
+
+		_, err = tx.Exec(rebind(driver, stmt), snippetID, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadTags fills in the Tags field of each snippet, using a single query for
+// all of them. The tags of each snippet are sorted by name.
+func loadTags(db *sql.DB, driver string, snippets []Snippet) error {
+	if len(snippets) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(snippets))
+	args := make([]any, len(snippets))
+
+	for i, s := range snippets {
+		index[s.ID] = i
+		args[i] = s.ID
+	}
+
+	stmt := `SELECT st.snippet_id, t.name FROM snippet_tags st
+	JOIN tags t ON t.id = st.tag_id
+	WHERE st.snippet_id IN (?` + strings.Repeat(", ?", len(snippets)-1) + `)
+	ORDER BY t.name`
+
+	rows, err := db.Query(rebind(driver, stmt), args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id  int
+			tag string
+		)
+
+		err = rows.Scan(&id, &tag)
+		if err != nil {
+			return err
+		}
+
+		i := index[id]
+		snippets[i].Tags = append(snippets[i].Tags, tag)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Token scopes. A read token can only fetch snippets through the API, while a
+// write token can also create them.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// Define a Token type to hold the data for a personal API token. The
+// plain-text token itself is never stored.
+type Token struct {
+	ID       int
+	UserID   int
+	Name     string
+	Scope    string
+	Created  time.Time
+	LastUsed *time.Time
+}
+
+// Allows() returns true if the token may be used for actions requiring the
+// given scope. The write scope includes the read scope.
+func (t Token) Allows(scope string) bool {
+	return t.Scope == ScopeWrite || t.Scope == scope
+}
+
+// Define a TokenModel type which wraps a sql.DB connection pool. Like the
+// UserModel, Driver is the name of the database/sql driver in use.
+type TokenModel struct {
+	DB     *sql.DB
+	Driver string
+}
+
+// This will create a new API token for a user and return the plain-text
+// token. Only a hash of it is stored in the database.
+func (m *TokenModel) Insert(userID int, name string, scope string) (string, error) {
+	stmt := `INSERT INTO tokens (user_id, name, hash, scope, created)
+	VALUES(?, ?, ?, ?, ?)`
+
+	token, hash, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = m.DB.Exec(rebind(m.Driver, stmt), userID, name, hash, scope, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// This will return all the API tokens belonging to a user, newest first.
+func (m *TokenModel) List(userID int) ([]Token, error) {
+	stmt := `SELECT id, user_id, name, scope, created, last_used FROM tokens
+	WHERE user_id = ? ORDER BY id DESC`
+
+	rows, err := m.DB.Query(rebind(m.Driver, stmt), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tokens []Token
+
+	for rows.Next() {
+		var t Token
+
+		err = rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &t.Created, &t.LastUsed)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// This will revoke (delete) one of a user's API tokens. If the user has no
+// token with that id we return the ErrNoRecord error.
+func (m *TokenModel) Revoke(id int, userID int) error {
+	stmt := `DELETE FROM tokens WHERE id = ? AND user_id = ?`
+
+	result, err := m.DB.Exec(rebind(m.Driver, stmt), id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// This will look up the token matching a plain-text API token and record
+// that it has just been used. If there is no such token we return the
+// ErrInvalidCredentials error.
+func (m *TokenModel) Authenticate(token string) (Token, error) {
+	stmt := `SELECT id, user_id, name, scope, created, last_used FROM tokens
+	WHERE hash = ?`
+
+	var t Token
+
+	err := m.DB.QueryRow(rebind(m.Driver, stmt), hashSecret(token)).Scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &t.Created, &t.LastUsed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Token{}, ErrInvalidCredentials
+		} else {
+			return Token{}, err
+		}
+	}
+
+	stmt = `UPDATE tokens SET last_used = ? WHERE id = ?`
+
+	_, err = m.DB.Exec(rebind(m.Driver, stmt), time.Now().UTC(), t.ID)
+	if err != nil {
+		return Token{}, err
+	}
+
+	return t, nil
+}
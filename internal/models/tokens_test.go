@@ -0,0 +1,105 @@
+package models_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/Galbeyte1/snippetbox/internal/models"
+)
+
+func TestMemoryTokenModel(t *testing.T) {
+	testTokenStore(t, models.NewMemoryTokenModel(), 1, 2)
+}
+
+func TestTokenModel(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *sql.DB, driver string) {
+		userID := newTestUser(t, db, driver)
+		otherID := newTestUser(t, db, driver)
+
+		testTokenStore(t, &models.TokenModel{DB: db, Driver: driver}, userID, otherID)
+	})
+}
+
+// testTokenStore runs the checks which every TokenStore implementation must
+// pass, for two different users.
+func testTokenStore(t *testing.T, tokens models.TokenStore, userID int, otherID int) {
+	readToken, err := tokens.Insert(userID, "laptop", models.ScopeRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeToken, err := tokens.Insert(userID, "server", models.ScopeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if readToken == "" || readToken == writeToken {
+		t.Fatalf("got tokens %q and %q; want two different tokens", readToken, writeToken)
+	}
+
+	_, err = tokens.Insert(otherID, "other", models.ScopeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := tokens.List(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The user's own tokens, newest first, neither of them used yet.
+	if len(list) != 2 || list[0].Name != "server" || list[1].Name != "laptop" {
+		t.Fatalf("got tokens %+v; want server and laptop", list)
+	}
+
+	for _, token := range list {
+		if token.UserID != userID || token.Created.IsZero() || token.LastUsed != nil {
+			t.Errorf("got token %+v; want an unused token of user %d", token, userID)
+		}
+	}
+
+	token, err := tokens.Authenticate(readToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.ID != list[1].ID || token.Scope != models.ScopeRead {
+		t.Errorf("got token %+v; want the read token", token)
+	}
+
+	list, err = tokens.List(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list[1].LastUsed == nil || list[0].LastUsed != nil {
+		t.Errorf("got tokens %+v; want only the read token marked as used", list)
+	}
+
+	_, err = tokens.Authenticate("not" + readToken)
+	if !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Errorf("got error %v for an unknown token; want ErrInvalidCredentials", err)
+	}
+
+	// Users can only revoke their own tokens.
+	err = tokens.Revoke(list[1].ID, otherID)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("got error %v revoking another user's token; want ErrNoRecord", err)
+	}
+
+	err = tokens.Revoke(list[1].ID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tokens.Authenticate(readToken)
+	if !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Errorf("got error %v for a revoked token; want ErrInvalidCredentials", err)
+	}
+
+	err = tokens.Revoke(list[1].ID, userID)
+	if !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("got error %v revoking twice; want ErrNoRecord", err)
+	}
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/crypto/bcrypt"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Define a new User struct. Notice how the field names and types align
+// with the columns in the database "users" table?
+type User struct {
+	ID             int
+	Name           string
+	Email          string
+	HashedPassword []byte
+	Created        time.Time
+}
+
+// Define a new UserModel struct which wraps a database connection pool.
+// Driver is the name of the database/sql driver in use, which we need to know
+// to write the query placeholders in the right style.
+type UserModel struct {
+	DB     *sql.DB
+	Driver string
+}
+
+// We'll use the Insert method to add a new record to the "users" table.
+func (m *UserModel) Insert(name, email, password string) error {
+	// Create a bcrypt hash of the plain-text password.
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		return err
+	}
+
+	// The created time is passed in as a parameter, rather than using a
+	// database function like UTC_TIMESTAMP(), so that the same query works on
+	// every database we support.
+	stmt := `INSERT INTO users (name, email, hashed_password, created)
+	VALUES(?, ?, ?, ?)`
+
+	// Use the Exec() method to insert the user details and hashed password
+	// into the users table.
+	_, err = m.DB.Exec(rebind(m.Driver, stmt), name, email, string(hashedPassword), time.Now().UTC())
+	if err != nil {
+		if isDuplicateEmail(err) {
+			return ErrDuplicateEmail
+		}
+		return err
+	}
+
+	return nil
+}
+
+// isDuplicateEmail returns true if err is a unique constraint violation on
+// the users email column.
+func isDuplicateEmail(err error) bool {
+	// We use the errors.As() function to check whether the error has the type
+	// *mysql.MySQLError. If it does, the error will be assigned to the
+	// mySQLError variable. We can then check whether or not the error relates
+	// to our users_uc_email key by checking if the error code equals 1062 and
+	// the contents of the error message string.
+	var mySQLError *mysql.MySQLError
+	if errors.As(err, &mySQLError) {
+		return mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users_uc_email")
+	}
+
+	// SQLite reports the violated column (rather than the constraint name) in
+	// its error message.
+	var sqliteError *sqlite.Error
+	if errors.As(err, &sqliteError) {
+		return sqliteError.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE && strings.Contains(sqliteError.Error(), "users.email")
+	}
+
+	// PostgreSQL uses the error code 23505 for unique violations, and tells us
+	// the name of the constraint.
+	var pgError *pgconn.PgError
+	if errors.As(err, &pgError) {
+		return pgError.Code == "23505" && pgError.ConstraintName == "users_uc_email"
+	}
+
+	return false
+}
+
+// We'll use the Authenticate method to verify whether a user exists with
+// the provided email address and password. This will return the relevant
+// user ID if they do.
+func (m *UserModel) Authenticate(email, password string) (int, error) {
+	// Retrieve the id and hashed password associated with the given email. If
+	// no matching email exists we return the ErrInvalidCredentials error.
+	var id int
+	var hashedPassword []byte
+
+	stmt := "SELECT id, hashed_password FROM users WHERE email = ?"
+
+	err := m.DB.QueryRow(rebind(m.Driver, stmt), email).Scan(&id, &hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		} else {
+			return 0, err
+		}
+	}
+
+	// Check whether the hashed password and plain-text password provided match.
+	// If they don't, we return the ErrInvalidCredentials error.
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return 0, ErrInvalidCredentials
+		} else {
+			return 0, err
+		}
+	}
+
+	// Otherwise, the password is correct. Return the user ID.
+	return id, nil
+}
+
+// We'll use the Exists method to check if a user exists with a specific ID.
+func (m *UserModel) Exists(id int) (bool, error) {
+	var exists bool
+
+	stmt := "SELECT EXISTS(SELECT true FROM users WHERE id = ?)"
+
+	err := m.DB.QueryRow(rebind(m.Driver, stmt), id).Scan(&exists)
+	return exists, err
+}
+
+// We'll use the Get method to fetch the details of a specific user, leaving
+// out their hashed password. If there is no such user we return the
+// ErrNoRecord error.
+func (m *UserModel) Get(id int) (User, error) {
+	var user User
+
+	stmt := "SELECT id, name, email, created FROM users WHERE id = ?"
+
+	err := m.DB.QueryRow(rebind(m.Driver, stmt), id).Scan(&user.ID, &user.Name, &user.Email, &user.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, ErrNoRecord
+		} else {
+			return User{}, err
+		}
+	}
+
+	return user, nil
+}
@@ -0,0 +1,95 @@
+package models_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Galbeyte1/snippetbox/internal/models"
+)
+
+func TestMemoryUserModel(t *testing.T) {
+	testUserStore(t, models.NewMemoryUserModel())
+}
+
+func TestUserModel(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *sql.DB, driver string) {
+		testUserStore(t, &models.UserModel{DB: db, Driver: driver})
+	})
+}
+
+// forEachDatabase runs a subtest against a new SQLite database, and against
+// the MySQL and PostgreSQL test databases if their DSNs are set (see
+// TestSnippetModel).
+func forEachDatabase(t *testing.T, test func(t *testing.T, db *sql.DB, driver string)) {
+	dsns := []struct {
+		driver string
+		dsn    string
+	}{
+		{"sqlite", "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=foreign_keys(1)"},
+		{"mysql", os.Getenv("SNIPPETBOX_TEST_MYSQL_DSN")},
+		{"pgx", os.Getenv("SNIPPETBOX_TEST_POSTGRES_DSN")},
+	}
+
+	for _, d := range dsns {
+		t.Run(d.driver, func(t *testing.T) {
+			if d.dsn == "" {
+				t.Skipf("no test database for %s", d.driver)
+			}
+
+			test(t, newTestDB(t, d.driver, d.dsn), d.driver)
+		})
+	}
+}
+
+// testUserStore runs the checks which every UserStore implementation must
+// pass.
+func testUserStore(t *testing.T, users models.UserStore) {
+	email := fmt.Sprintf("user-test-%d@example.com", time.Now().UnixNano())
+
+	err := users.Insert("User Test", email, "pa55word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = users.Insert("Someone Else", email, "other password")
+	if !errors.Is(err, models.ErrDuplicateEmail) {
+		t.Errorf("got error %v for a duplicate email; want ErrDuplicateEmail", err)
+	}
+
+	id, err := users.Authenticate(email, "pa55word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = users.Authenticate(email, "wrong password")
+	if !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Errorf("got error %v for the wrong password; want ErrInvalidCredentials", err)
+	}
+
+	_, err = users.Authenticate("nobody-"+email, "pa55word")
+	if !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Errorf("got error %v for an unknown email; want ErrInvalidCredentials", err)
+	}
+
+	for _, tt := range []struct {
+		id   int
+		want bool
+	}{
+		{id, true},
+		{id + 1000, false},
+	} {
+		exists, err := users.Exists(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if exists != tt.want {
+			t.Errorf("got %t for user %d existing; want %t", exists, tt.id, tt.want)
+		}
+	}
+}
@@ -5,4 +5,58 @@ for (var i = 0; i < navLinks.length; i++) {
 		link.classList.add("live");
 		break;
 	}
-}
\ No newline at end of file
+}
+
+// Highlight the lines of a snippet selected by the URL fragment, which is
+// either a single line like #L10 or a range like #L10-L20.
+function highlightLines() {
+	var lines = document.querySelectorAll(".chroma .line");
+	for (var i = 0; i < lines.length; i++) {
+		lines[i].classList.remove("hl");
+	}
+
+	var match = window.location.hash.match(/^#L(\d+)(?:-L(\d+))?$/);
+	if (!match) {
+		return;
+	}
+
+	var start = parseInt(match[1], 10);
+	var end = match[2] ? parseInt(match[2], 10) : start;
+	if (end < start) {
+		var tmp = start;
+		start = end;
+		end = tmp;
+	}
+
+	// Line numbers start at 1, so treat a #L0 in the fragment as #L1.
+	start = Math.max(start, 1);
+
+	for (var n = start; n <= end && n <= lines.length; n++) {
+		lines[n - 1].classList.add("hl");
+	}
+
+	if (lines[start - 1]) {
+		lines[start - 1].scrollIntoView({block: "center"});
+	}
+}
+
+// Shift-clicking a line number extends the selection from the line in the
+// current fragment to a range.
+var lineLinks = document.querySelectorAll(".chroma .lnlinks");
+for (var i = 0; i < lineLinks.length; i++) {
+	lineLinks[i].addEventListener("click", function(event) {
+		var current = window.location.hash.match(/^#L(\d+)/);
+		if (!event.shiftKey || !current) {
+			return;
+		}
+
+		event.preventDefault();
+
+		var start = parseInt(current[1], 10);
+		var end = parseInt(this.getAttribute("href").slice(2), 10);
+		window.location.hash = "#L" + Math.min(start, end) + "-L" + Math.max(start, end);
+	});
+}
+
+window.addEventListener("hashchange", highlightLines);
+highlightLines();